@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/admin"
+)
+
+// adminAddr is the address of the local admin API, overriding admin_address in the configuration
+var adminAddr string
+
+// startAdminAPI menjalankan API admin lokal jika alamatnya dikonfigurasi dan
+// mengembalikan fungsi untuk menghentikannya
+func startAdminAPI() func() {
+	addr := adminAddr
+	if addr == "" {
+		addr = Container.Config.AdminAddress
+	}
+	if addr == "" {
+		return func() {}
+	}
+
+	server := admin.NewServer(Container.Client, Container.TunnelService, Container.Logger)
+	listenAddr, err := server.Start(addr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "🛠️  Admin API: http://%s/api/tunnels\n", listenAddr)
+
+	return func() { server.Close() }
+}
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&adminAddr, "admin-addr", "", "Alamat API admin lokal, misalnya 127.0.0.1:4040 (default: admin_address di konfigurasi)")
+}
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
@@ -45,14 +47,76 @@ var configShowCmd = &cobra.Command{
 			for i, tunnel := range Container.Config.Tunnels {
 				fmt.Printf("  %d. %s (%s)\n", i+1, tunnel.Name, tunnel.Type)
 				fmt.Printf("     Local Port: %d\n", tunnel.LocalPort)
+				if tunnel.Upstream != "" {
+					fmt.Printf("     Upstream: %s\n", tunnel.Upstream)
+				}
 				if tunnel.Type == model.TunnelTypeHTTP {
 					fmt.Printf("     Subdomain: %s\n", tunnel.Subdomain)
-				} else if tunnel.Type == model.TunnelTypeTCP {
+					if tunnel.Hostname != "" {
+						fmt.Printf("     Hostname: %s\n", tunnel.Hostname)
+					}
+					if tunnel.Scheme != "" {
+						fmt.Printf("     Scheme: %s\n", tunnel.Scheme)
+					}
+					if tunnel.HostHeader != "" {
+						fmt.Printf("     Host Header: %s\n", tunnel.HostHeader)
+					}
+					for _, route := range tunnel.Routes {
+						fmt.Printf("     Route: %s\n", route)
+					}
+					if len(tunnel.Upstreams) > 0 {
+						fmt.Printf("     Upstreams: %s\n", strings.Join(tunnel.Upstreams, ", "))
+					}
+					if tunnel.Verify != nil {
+						fmt.Printf("     Webhook Verification: %s\n", tunnel.Verify.Provider)
+					}
+					if tunnel.RateLimit != nil {
+						fmt.Printf("     Rate Limit: %s\n", formatRateLimit(tunnel.RateLimit))
+					}
+					if tunnel.MockFile != "" {
+						fmt.Printf("     Mock File: %s\n", tunnel.MockFile)
+					}
+					if tunnel.Faults != nil {
+						fmt.Printf("     Fault Injection: %s\n", formatFaults(tunnel.Faults))
+					}
+					if tunnel.Protocol != "" {
+						fmt.Printf("     Protocol: %s\n", tunnel.Protocol)
+					}
+					if tunnel.CORS != nil {
+						fmt.Printf("     CORS: %s\n", formatCORS(tunnel.CORS))
+					}
+				} else if tunnel.Type == model.TunnelTypeTLS {
+					fmt.Printf("     Hostname: %s\n", tunnel.Hostname)
+					fmt.Printf("     TLS: %s\n", formatTLSTermination(tunnel.TLSTermination))
+				} else if tunnel.Type == model.TunnelTypeTCP || tunnel.Type == model.TunnelTypeUDP {
 					fmt.Printf("     Remote Port: %d\n", tunnel.RemotePort)
+					if tunnel.UDP != nil && tunnel.UDP.IdleTimeout > 0 {
+						fmt.Printf("     Session Idle Timeout: %s\n", tunnel.UDP.IdleTimeout)
+					}
 				}
 				if tunnel.Auth != nil {
 					fmt.Printf("     Auth: %s\n", tunnel.Auth.Type)
 				}
+				if tunnel.IPPolicy != nil {
+					if len(tunnel.IPPolicy.Allow) > 0 {
+						fmt.Printf("     Allow IP: %s\n", strings.Join(tunnel.IPPolicy.Allow, ", "))
+					}
+					if len(tunnel.IPPolicy.Deny) > 0 {
+						fmt.Printf("     Deny IP: %s\n", strings.Join(tunnel.IPPolicy.Deny, ", "))
+					}
+				}
+			}
+		}
+
+		// Display custom domains
+		if len(Container.Config.Domains) > 0 {
+			fmt.Println("\nCustom Domains:")
+			for _, domain := range Container.Config.Domains {
+				status := "pending verification"
+				if domain.Verified {
+					status = "verified"
+				}
+				fmt.Printf("  - %s (%s)\n", domain.Name, status)
 			}
 		}
 	},
@@ -113,7 +177,11 @@ var configAddTunnelCmd = &cobra.Command{
 	Long: `Add tunnel to Haxorport Client configuration.
 Examples:
   haxor config add-tunnel --name web --type http --port 8080 --subdomain myapp
-  haxor config add-tunnel --name ssh --type tcp --port 22 --remote-port 2222`,
+  haxor config add-tunnel --name site --type http --port 8080 --hostname app.example.com
+  haxor config add-tunnel --name ssh --type tcp --port 22 --remote-port 2222
+  haxor config add-tunnel --name wg --type udp --port 51820 --udp-idle-timeout 5m
+  haxor config add-tunnel --name api --type tls --port 8443 --hostname api.example.com
+  haxor config add-tunnel --name app --type tls --port 8080 --hostname app.example.com --cert app.pem --key app-key.pem`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Validate parameters
 		if httpLocalPort <= 0 {
@@ -130,8 +198,9 @@ Examples:
 				auth.Type = model.AuthTypeBasic
 				auth.Username = httpUsername
 				auth.Password = httpPassword
-				if auth.Username == "" || auth.Password == "" {
-					fmt.Println("Error: Username and password are required for basic auth")
+				auth.PasswordHash = httpPassHash
+				if auth.Username == "" || (auth.Password == "" && auth.PasswordHash == "") {
+					fmt.Println("Error: Username and password (or password hash) are required for basic auth")
 					os.Exit(1)
 				}
 			case "header":
@@ -142,6 +211,38 @@ Examples:
 					fmt.Println("Error: Header name and value are required for header auth")
 					os.Exit(1)
 				}
+			case "oidc":
+				auth.Type = model.AuthTypeOIDC
+				auth.Issuer = httpOIDCIssuer
+				auth.ClientID = httpOIDCClient
+				auth.ClientSecret = httpOIDCSecret
+				auth.AllowedEmails = httpOIDCEmails
+				auth.AllowedDomains = httpOIDCDomain
+				auth.CookieSecret = httpOIDCCookie
+				if auth.Issuer == "" || auth.ClientID == "" {
+					fmt.Println("Error: Issuer and client ID are required for oidc auth")
+					os.Exit(1)
+				}
+			case "jwt":
+				auth.Type = model.AuthTypeJWT
+				auth.JWTSecret = httpJWTSecret
+				auth.JWTPublicKey = httpJWTPubKey
+				auth.JWKS = httpJWKS
+				auth.Issuer = httpJWTIssuer
+				auth.Audience = httpJWTAud
+				if auth.JWTSecret == "" && auth.JWTPublicKey == "" && auth.JWKS == "" {
+					fmt.Println("Error: Secret, public key or JWKS is required for jwt auth")
+					os.Exit(1)
+				}
+				var err error
+				if auth.RequiredClaims, err = parseKeyValues(httpJWTClaims); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if auth.ClaimHeaders, err = parseKeyValues(httpJWTHeaders); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
 			default:
 				fmt.Printf("Error: Invalid auth type: %s\n", httpAuthType)
 				os.Exit(1)
@@ -160,16 +261,69 @@ Examples:
 		case "http":
 			tunnelConfig.Type = model.TunnelTypeHTTP
 			tunnelConfig.Subdomain = httpSubdomain
+			tunnelConfig.Hostname = tlsHostname
 		case "tcp":
 			tunnelConfig.Type = model.TunnelTypeTCP
 			tunnelConfig.RemotePort = tcpRemotePort
+		case "tls":
+			if tlsHostname == "" {
+				fmt.Println("Error: Hostname is required for TLS tunnels")
+				os.Exit(1)
+			}
+			termination, err := tlsTerminationConfig()
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			tunnelConfig.Type = model.TunnelTypeTLS
+			tunnelConfig.Hostname = tlsHostname
+			tunnelConfig.TLSTermination = termination
+		case "udp":
+			tunnelConfig.Type = model.TunnelTypeUDP
+			tunnelConfig.RemotePort = tcpRemotePort
+			tunnelConfig.UDP = udpConfig(udpIdleTimeout, udpMaxSessions)
 		default:
 			fmt.Printf("Error: Invalid tunnel type: %s\n", tunnelType)
 			os.Exit(1)
 		}
 
-		// Set auth
+		// Set auth and IP policy
 		tunnelConfig.Auth = auth
+		tunnelConfig.IPPolicy = ipPolicyConfig()
+
+		// Set host header mode and routes
+		if tunnelConfig.Type == model.TunnelTypeHTTP {
+			tunnelConfig.HostHeader = httpHostHeader
+			routes, err := parseRoutes(httpRoutes)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			tunnelConfig.Routes = routes
+			tunnelConfig.Upstreams = httpUpstreams
+			tunnelConfig.LoadBalance = model.LoadBalanceStrategy(httpLBStrategy)
+			tunnelConfig.HashHeader = httpLBHeader
+			tunnelConfig.HealthCheck = healthCheckConfig(httpHealthPath, httpHealthInt)
+			tunnelConfig.Scheme = httpScheme
+			tunnelConfig.UpstreamTLS = upstreamTLSConfig(httpUpstreamCA, httpInsecure, httpClientCert, httpClientKey)
+			tunnelConfig.RewriteURLs = httpRewriteURL
+			tunnelConfig.RewriteHeaders = httpRewriteHdr
+			tunnelConfig.RateLimit = rateLimitConfig(httpRateLimit, httpRateBurst, httpIPRate, httpIPBurst, httpMaxFlight)
+			tunnelConfig.Verify = webhookVerifyConfig(httpVerify, httpVerifySec, httpVerifyHdr, httpVerifyTol)
+			tunnelConfig.Timeouts = upstreamTimeoutConfig()
+			tunnelConfig.MockFile = httpMockFile
+			tunnelConfig.Faults = faultConfig()
+			tunnelConfig.CORS = corsConfig()
+			tunnelConfig.Protocol = model.UpstreamProtocol(httpProtocol)
+			if tunnelConfig.MaxRequestBody, tunnelConfig.MaxResponseBody, err = bodyLimits(httpMaxReqBody, httpMaxResBody); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if tunnelConfig.ErrorPages, err = parseKeyValues(httpErrorPages); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
 		// Add tunnel to configuration
 		Container.ConfigService.AddTunnel(Container.Config, tunnelConfig)
@@ -211,6 +365,26 @@ Examples:
 	},
 }
 
+// configHashPasswordCmd is the command to create a bcrypt password hash for basic auth
+var configHashPasswordCmd = &cobra.Command{
+	Use:   "hash-password [password]",
+	Short: "Create a bcrypt hash for basic auth",
+	Long: `Create a bcrypt hash of a basic auth password, to be stored as password_hash
+in config.yaml instead of the plaintext password.
+Examples:
+  haxor config hash-password my-secret`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hash, err := bcrypt.GenerateFromPassword([]byte(args[0]), bcrypt.DefaultCost)
+		if err != nil {
+			fmt.Printf("Error: Failed to hash password: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println(string(hash))
+	},
+}
+
 // maskString hides part of a string
 func maskString(s string) string {
 	if len(s) <= 4 {
@@ -225,18 +399,71 @@ func init() {
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configAddTunnelCmd)
 	configCmd.AddCommand(configRemoveTunnelCmd)
+	configCmd.AddCommand(configHashPasswordCmd)
 
 	// Tambahkan flag untuk add-tunnel
 	configAddTunnelCmd.Flags().StringP("name", "n", "", "Nama tunnel")
-	configAddTunnelCmd.Flags().StringP("type", "t", "", "Tipe tunnel (http, tcp)")
+	configAddTunnelCmd.Flags().StringP("type", "t", "", "Tipe tunnel (http, tcp, udp, tls)")
 	configAddTunnelCmd.Flags().IntVarP(&httpLocalPort, "port", "p", 0, "Port lokal yang akan di-tunnel")
 	configAddTunnelCmd.Flags().StringVarP(&httpSubdomain, "subdomain", "s", "", "Subdomain yang diminta (untuk HTTP)")
-	configAddTunnelCmd.Flags().IntVarP(&tcpRemotePort, "remote-port", "r", 0, "Port remote yang diminta (untuk TCP)")
-	configAddTunnelCmd.Flags().StringVarP(&httpAuthType, "auth", "a", "", "Tipe autentikasi (basic, header)")
+	configAddTunnelCmd.Flags().IntVarP(&tcpRemotePort, "remote-port", "r", 0, "Port remote yang diminta (untuk TCP dan UDP)")
+	configAddTunnelCmd.Flags().StringVar(&tlsHostname, "hostname", "", "Domain kustom untuk tunnel HTTP, atau hostname yang dirutekan ke tunnel TLS berdasarkan SNI")
+	configAddTunnelCmd.Flags().StringArrayVar(&tlsCertFiles, "cert", nil, "Terminasi TLS di klien dengan file sertifikat ini (PEM, dapat diulang)")
+	configAddTunnelCmd.Flags().StringArrayVar(&tlsKeyFiles, "key", nil, "File private key (PEM) untuk sertifikat pada urutan yang sama")
+	configAddTunnelCmd.Flags().DurationVar(&udpIdleTimeout, "udp-idle-timeout", 0, "Tutup sesi UDP yang tidak aktif selama durasi ini (default 60s)")
+	configAddTunnelCmd.Flags().IntVar(&udpMaxSessions, "max-sessions", 0, "Jumlah maksimum sesi UDP bersamaan (default 1024)")
+	configAddTunnelCmd.Flags().StringVarP(&httpAuthType, "auth", "a", "", "Tipe autentikasi (basic, header, oidc, jwt)")
 	configAddTunnelCmd.Flags().StringVarP(&httpUsername, "username", "u", "", "Username untuk autentikasi basic")
 	configAddTunnelCmd.Flags().StringVarP(&httpPassword, "password", "w", "", "Password untuk autentikasi basic")
+	configAddTunnelCmd.Flags().StringVar(&httpPassHash, "password-hash", "", "Hash bcrypt password untuk autentikasi basic (lihat 'haxor config hash-password')")
 	configAddTunnelCmd.Flags().StringVar(&httpHeader, "header", "", "Nama header untuk autentikasi header")
 	configAddTunnelCmd.Flags().StringVar(&httpValue, "value", "", "Nilai header untuk autentikasi header")
+	configAddTunnelCmd.Flags().StringVar(&httpOIDCIssuer, "oidc-issuer", "", "URL issuer OpenID Connect untuk autentikasi oidc")
+	configAddTunnelCmd.Flags().StringVar(&httpOIDCClient, "oidc-client-id", "", "Client ID OpenID Connect")
+	configAddTunnelCmd.Flags().StringVar(&httpOIDCSecret, "oidc-client-secret", "", "Client secret OpenID Connect")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpOIDCEmails, "oidc-allow-email", nil, "Email yang diizinkan login (dapat diulang)")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpOIDCDomain, "oidc-allow-domain", nil, "Domain email yang diizinkan login (dapat diulang)")
+	configAddTunnelCmd.Flags().StringVar(&httpOIDCCookie, "oidc-cookie-secret", "", "Secret untuk menandatangani cookie sesi (sesi tetap berlaku setelah restart)")
+	configAddTunnelCmd.Flags().StringVar(&httpJWTSecret, "jwt-secret", "", "Secret HMAC untuk verifikasi token JWT (HS256/384/512)")
+	configAddTunnelCmd.Flags().StringVar(&httpJWTPubKey, "jwt-public-key", "", "File public key atau sertifikat (PEM) untuk verifikasi token JWT")
+	configAddTunnelCmd.Flags().StringVar(&httpJWKS, "jwks", "", "File atau URL JWKS untuk verifikasi token JWT")
+	configAddTunnelCmd.Flags().StringVar(&httpJWTIssuer, "jwt-issuer", "", "Nilai klaim iss yang wajib")
+	configAddTunnelCmd.Flags().StringVar(&httpJWTAud, "jwt-audience", "", "Nilai klaim aud yang wajib")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpJWTClaims, "jwt-claim", nil, "Klaim wajib, format NAMA=NILAI atau NAMA (dapat diulang)")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpJWTHeaders, "jwt-claim-header", nil, "Teruskan klaim sebagai header, format KLAIM=HEADER (dapat diulang)")
+	configAddTunnelCmd.Flags().StringVar(&httpHostHeader, "host-header", "", "Header Host ke layanan lokal (rewrite, preserve, atau nilai kustom; default rewrite)")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpRoutes, "route", nil, "Route path ke layanan lokal lain, format PATTERN=TARGET[,strip] (dapat diulang)")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpUpstreams, "upstream", nil, "Alamat upstream untuk load balancing, misal localhost:3000 (dapat diulang)")
+	configAddTunnelCmd.Flags().StringVar(&httpLBStrategy, "lb", "", "Strategi load balancing (round_robin, least_conn, hash)")
+	configAddTunnelCmd.Flags().StringVar(&httpLBHeader, "lb-header", "", "Header untuk strategi load balancing hash")
+	configAddTunnelCmd.Flags().StringVar(&httpHealthPath, "health-path", "", "Path health check HTTP untuk upstream (kosong untuk cek TCP)")
+	configAddTunnelCmd.Flags().DurationVar(&httpHealthInt, "health-interval", 0, "Interval health check upstream (mengaktifkan health check)")
+	configAddTunnelCmd.Flags().StringVar(&httpScheme, "scheme", "", "Skema layanan lokal (http, https)")
+	configAddTunnelCmd.Flags().StringVar(&httpUpstreamCA, "upstream-ca", "", "File CA (PEM) untuk verifikasi layanan lokal HTTPS")
+	configAddTunnelCmd.Flags().StringVar(&httpProtocol, "protocol", "", "Protokol ke layanan lokal (auto, http1, h2c untuk gRPC tanpa TLS)")
+	configAddTunnelCmd.Flags().BoolVar(&httpInsecure, "upstream-insecure", false, "Lewati verifikasi sertifikat layanan lokal HTTPS")
+	configAddTunnelCmd.Flags().StringVar(&httpClientCert, "upstream-cert", "", "Sertifikat klien (PEM) untuk layanan lokal HTTPS")
+	configAddTunnelCmd.Flags().StringVar(&httpClientKey, "upstream-key", "", "Kunci sertifikat klien (PEM) untuk layanan lokal HTTPS")
+	configAddTunnelCmd.Flags().BoolVar(&httpRewriteURL, "rewrite-urls", false, "Ganti URL lokal dalam respons HTML/CSS dengan URL tunnel")
+	configAddTunnelCmd.Flags().BoolVar(&httpRewriteHdr, "rewrite-headers", false, "Sesuaikan header Location, Refresh dan Set-Cookie dengan URL tunnel")
+
+	configAddTunnelCmd.Flags().Float64Var(&httpRateLimit, "rate-limit", 0, "Batas permintaan per detik untuk seluruh tunnel (0 untuk tanpa batas)")
+	configAddTunnelCmd.Flags().IntVar(&httpRateBurst, "rate-burst", 0, "Jumlah permintaan sekaligus di atas batas laju tunnel")
+	configAddTunnelCmd.Flags().Float64Var(&httpIPRate, "ip-rate-limit", 0, "Batas permintaan per detik untuk setiap IP pengunjung (0 untuk tanpa batas)")
+	configAddTunnelCmd.Flags().IntVar(&httpIPBurst, "ip-rate-burst", 0, "Jumlah permintaan sekaligus di atas batas laju per IP")
+	configAddTunnelCmd.Flags().IntVar(&httpMaxFlight, "max-in-flight", 0, "Jumlah maksimum permintaan bersamaan ke layanan lokal (0 untuk tanpa batas)")
+	configAddTunnelCmd.Flags().StringVar(&httpVerify, "verify", "", "Verifikasi tanda tangan webhook (github, stripe, slack, twilio, shopify, generic)")
+	configAddTunnelCmd.Flags().StringVar(&httpVerifySec, "verify-secret", "", "Secret penandatanganan webhook (auth token untuk twilio)")
+	configAddTunnelCmd.Flags().StringVar(&httpVerifyHdr, "verify-header", "", "Header tanda tangan untuk verifikasi webhook generic")
+	configAddTunnelCmd.Flags().DurationVar(&httpVerifyTol, "verify-tolerance", 0, "Toleransi umur timestamp tanda tangan webhook (default 5m)")
+	configAddTunnelCmd.Flags().StringArrayVar(&httpErrorPages, "error-page", nil, "Template halaman error kustom dalam format KODE=FILE (dapat diulang)")
+	addUpstreamTimeoutFlags(configAddTunnelCmd)
+	addFaultFlags(configAddTunnelCmd, true)
+	configAddTunnelCmd.Flags().StringVar(&httpMaxReqBody, "max-request-body", "", "Ukuran maksimum body permintaan, misalnya 10MB (default 100MB)")
+	configAddTunnelCmd.Flags().StringVar(&httpMaxResBody, "max-response-body", "", "Ukuran maksimum body respons layanan lokal, misalnya 50MB (default 100MB)")
+	configAddTunnelCmd.Flags().StringVar(&httpMockFile, "mock-file", "", "File YAML berisi aturan mock yang dijawab langsung oleh client")
+	addIPPolicyFlags(configAddTunnelCmd)
+	addCORSFlags(configAddTunnelCmd)
 
 	// Tandai flag yang diperlukan
 	configAddTunnelCmd.MarkFlagRequired("type")
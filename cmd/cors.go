@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// CORS flags, shared by the http and config add-tunnel commands
+	corsOrigins     []string
+	corsMethods     []string
+	corsHeaders     []string
+	corsExpose      []string
+	corsCredentials bool
+	corsMaxAge      time.Duration
+)
+
+// addCORSFlags menambahkan flag kebijakan CORS ke sebuah perintah
+func addCORSFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&corsOrigins, "cors-origin", nil, "Origin yang diizinkan memanggil tunnel, misalnya https://app.example.com, https://*.example.com atau * (dapat diulang)")
+	cmd.Flags().StringSliceVar(&corsMethods, "cors-method", nil, "Metode yang diizinkan untuk permintaan lintas origin (default GET,HEAD,POST,PUT,PATCH,DELETE)")
+	cmd.Flags().StringSliceVar(&corsHeaders, "cors-header", nil, "Header permintaan yang diizinkan (default header yang diminta browser)")
+	cmd.Flags().StringSliceVar(&corsExpose, "cors-expose-header", nil, "Header respons yang boleh dibaca script")
+	cmd.Flags().BoolVar(&corsCredentials, "cors-credentials", false, "Izinkan cookie dan header autentikasi pada permintaan lintas origin")
+	cmd.Flags().DurationVar(&corsMaxAge, "cors-max-age", 0, "Lama browser menyimpan hasil preflight (default 10m)")
+}
+
+// corsConfig membuat kebijakan CORS dari flag, atau nil jika tidak ditentukan
+func corsConfig() *model.CORSConfig {
+	if len(corsOrigins) == 0 {
+		return nil
+	}
+	return &model.CORSConfig{
+		AllowOrigins:     corsOrigins,
+		AllowMethods:     corsMethods,
+		AllowHeaders:     corsHeaders,
+		ExposeHeaders:    corsExpose,
+		AllowCredentials: corsCredentials,
+		MaxAge:           corsMaxAge,
+	}
+}
+
+// formatCORS menampilkan kebijakan CORS dalam satu baris
+func formatCORS(config *model.CORSConfig) string {
+	text := strings.Join(config.AllowOrigins, ", ")
+	if config.AllowCredentials {
+		text += " (credentials)"
+	}
+	return text
+}
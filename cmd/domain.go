@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/spf13/cobra"
+)
+
+// domainCmd is the command to manage custom domains
+var domainCmd = &cobra.Command{
+	Use:   "domain",
+	Short: "Manage custom domains",
+	Long: `Manage the custom domains used as hostnames of HTTP and TLS tunnels.
+Custom domains require a subscription with the Custom Domains feature.
+Examples:
+  haxor domain add app.example.com
+  haxor domain verify app.example.com
+  haxor http --port 8080 --hostname app.example.com`,
+}
+
+// domainAddCmd is the command to add a custom domain
+var domainAddCmd = &cobra.Command{
+	Use:   "add [domain]",
+	Short: "Add a custom domain",
+	Long: `Add a custom domain and show the DNS records that verify it.
+Example:
+  haxor domain add app.example.com`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireCustomDomains()
+
+		domain := Container.Config.GetDomain(args[0])
+		if domain == nil {
+			created, err := Container.DomainService.NewDomain(args[0])
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			Container.Config.AddDomain(created)
+			saveDomains()
+			domain = &created
+			fmt.Printf("Domain %s added.\n", domain.Name)
+		} else {
+			fmt.Printf("Domain %s has already been added.\n", domain.Name)
+		}
+
+		printDomainRecords(*domain)
+	},
+}
+
+// domainListCmd is the command to list custom domains
+var domainListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List custom domains",
+	Long:  `List the custom domains and their verification status.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(Container.Config.Domains) == 0 {
+			fmt.Println("No custom domains. Add one with 'haxor domain add <domain>'.")
+			return
+		}
+
+		fmt.Println("Custom Domains:")
+		for i, domain := range Container.Config.Domains {
+			status := "pending verification"
+			if domain.Verified {
+				status = "verified"
+			}
+			fmt.Printf("  %d. %s (%s)\n", i+1, domain.Name, status)
+		}
+	},
+}
+
+// domainVerifyCmd is the command to verify the DNS records of a custom domain
+var domainVerifyCmd = &cobra.Command{
+	Use:   "verify [domain]",
+	Short: "Verify the DNS records of a custom domain",
+	Long: `Check that a custom domain points to haxorport and that its TXT record
+contains the verification token shown by 'haxor domain add'.
+Example:
+  haxor domain verify app.example.com`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		domain := Container.Config.GetDomain(args[0])
+		if domain == nil {
+			fmt.Printf("Error: Domain %s not found. Add it with 'haxor domain add %s'.\n", args[0], args[0])
+			os.Exit(1)
+		}
+
+		result := Container.DomainService.Verify(*domain, Container.Config.BaseDomain)
+		if result.Routed {
+			fmt.Printf("✓ %s points to %s\n", domain.Name, result.Target)
+		} else {
+			fmt.Printf("✗ %s\n", result.RouteError)
+		}
+		if result.Owned {
+			fmt.Printf("✓ TXT record %s found\n", domain.VerificationRecord())
+		} else {
+			fmt.Printf("✗ %s\n", result.OwnerError)
+		}
+
+		if domain.Verified != result.Verified() {
+			domain.Verified = result.Verified()
+			saveDomains()
+		}
+
+		if !result.Verified() {
+			fmt.Println("\nDomain is not verified yet. DNS changes can take a while to propagate.")
+			printDomainRecords(*domain)
+			os.Exit(1)
+		}
+		fmt.Printf("\nDomain %s verified. Use it with --hostname %s.\n", domain.Name, domain.Name)
+	},
+}
+
+// domainRemoveCmd is the command to remove a custom domain
+var domainRemoveCmd = &cobra.Command{
+	Use:   "remove [domain]",
+	Short: "Remove a custom domain",
+	Long: `Remove a custom domain from the configuration.
+Example:
+  haxor domain remove app.example.com`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := model.NormalizeHostname(args[0])
+		if !Container.Config.RemoveDomain(name) {
+			fmt.Printf("Error: Domain %s not found\n", name)
+			os.Exit(1)
+		}
+		saveDomains()
+
+		fmt.Printf("Domain %s removed.\n", name)
+		for _, tunnel := range Container.Config.Tunnels {
+			if model.NormalizeHostname(tunnel.Hostname) == name {
+				fmt.Printf("Warning: Tunnel %s still uses %s as its hostname\n", tunnel.Name, name)
+			}
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(domainCmd)
+	domainCmd.AddCommand(domainAddCmd)
+	domainCmd.AddCommand(domainListCmd)
+	domainCmd.AddCommand(domainVerifyCmd)
+	domainCmd.AddCommand(domainRemoveCmd)
+}
+
+// requireCustomDomains exits when the subscription of the user does not
+// include custom domains
+func requireCustomDomains() {
+	if !Container.Config.AuthEnabled {
+		return
+	}
+
+	if !Container.Client.IsConnected() {
+		if err := Container.Client.Connect(); err != nil {
+			fmt.Printf("Error: Failed to connect to server: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if err := Container.Client.CheckCustomDomains(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Upgrade your subscription to use custom domains: https://haxorport.online/pricing")
+		os.Exit(1)
+	}
+}
+
+// saveDomains saves the configuration after its domains changed
+func saveDomains() {
+	if err := Container.ConfigRepository.Save(Container.Config, ConfigPath); err != nil {
+		fmt.Printf("Error: Failed to save configuration: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printDomainRecords shows the DNS records that verify a custom domain
+func printDomainRecords(domain model.CustomDomain) {
+	fmt.Println("\nCreate these DNS records at your DNS provider:")
+	fmt.Printf("  CNAME  %s  →  %s\n", domain.Name, Container.Config.BaseDomain)
+	fmt.Printf("  TXT    %s  →  %s\n", domain.VerificationRecord(), domain.VerificationValue())
+	fmt.Println("\nApex domains cannot have a CNAME; use an ALIAS or ANAME record instead.")
+	fmt.Printf("Then run 'haxor domain verify %s'.\n", domain.Name)
+}
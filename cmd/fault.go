@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Fault injection flags, shared by the http, tcp and config add-tunnel commands
+	faultPaths     []string
+	faultProb      float64
+	faultLatency   time.Duration
+	faultJitter    time.Duration
+	faultErrorRate float64
+	faultStatuses  []int
+	faultDropRate  float64
+	faultBandwidth int
+	faultPaused    bool
+)
+
+// addFaultFlags menambahkan flag injeksi gangguan ke sebuah perintah; flag
+// yang hanya berlaku untuk HTTP ditambahkan jika httpOnly bernilai true
+func addFaultFlags(cmd *cobra.Command, httpOnly bool) {
+	cmd.Flags().Float64Var(&faultProb, "fault-probability", 0, "Peluang sebuah permintaan atau koneksi terkena gangguan, 0 sampai 1 (default 1)")
+	cmd.Flags().DurationVar(&faultLatency, "fault-latency", 0, "Latency tambahan yang disuntikkan")
+	cmd.Flags().DurationVar(&faultJitter, "fault-jitter", 0, "Latency acak tambahan hingga durasi ini")
+	cmd.Flags().Float64Var(&faultDropRate, "fault-drop-rate", 0, "Peluang koneksi diputus, 0 sampai 1")
+	cmd.Flags().IntVar(&faultBandwidth, "fault-bandwidth", 0, "Batasi bandwidth respons (byte per detik)")
+	cmd.Flags().BoolVar(&faultPaused, "fault-paused", false, "Mulai dengan injeksi gangguan nonaktif (aktifkan melalui API admin)")
+	if httpOnly {
+		cmd.Flags().StringArrayVar(&faultPaths, "fault-path", nil, "Glob path yang terkena gangguan, misalnya /api/** (dapat diulang)")
+		cmd.Flags().Float64Var(&faultErrorRate, "fault-error-rate", 0, "Peluang permintaan dijawab dengan error 5xx, 0 sampai 1")
+		cmd.Flags().IntSliceVar(&faultStatuses, "fault-status", nil, "Kode status error yang disuntikkan (default 500,502,503,504)")
+	}
+}
+
+// faultConfig membuat konfigurasi injeksi gangguan dari flag, atau nil jika tidak ditentukan
+func faultConfig() *model.FaultConfig {
+	if faultLatency == 0 && faultJitter == 0 && faultErrorRate == 0 && faultDropRate == 0 && faultBandwidth == 0 {
+		return nil
+	}
+	return &model.FaultConfig{
+		Paths:         faultPaths,
+		Probability:   faultProb,
+		Latency:       faultLatency,
+		Jitter:        faultJitter,
+		ErrorRate:     faultErrorRate,
+		ErrorStatuses: faultStatuses,
+		DropRate:      faultDropRate,
+		Bandwidth:     faultBandwidth,
+		Paused:        faultPaused,
+	}
+}
+
+// formatFaults menampilkan konfigurasi injeksi gangguan dalam satu baris
+func formatFaults(config *model.FaultConfig) string {
+	var parts []string
+	if config.Latency > 0 || config.Jitter > 0 {
+		parts = append(parts, fmt.Sprintf("latency %s±%s", config.Latency, config.Jitter))
+	}
+	if config.ErrorRate > 0 {
+		parts = append(parts, fmt.Sprintf("error %.0f%%", config.ErrorRate*100))
+	}
+	if config.DropRate > 0 {
+		parts = append(parts, fmt.Sprintf("drop %.0f%%", config.DropRate*100))
+	}
+	if config.Bandwidth > 0 {
+		parts = append(parts, fmt.Sprintf("%d B/s", config.Bandwidth))
+	}
+	if config.Probability > 0 && config.Probability < 1 {
+		parts = append(parts, fmt.Sprintf("probability %.0f%%", config.Probability*100))
+	}
+	if len(config.Paths) > 0 {
+		parts = append(parts, "paths "+strings.Join(config.Paths, ","))
+	}
+	if config.Paused {
+		parts = append(parts, "paused")
+	}
+	return strings.Join(parts, ", ")
+}
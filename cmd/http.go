@@ -3,10 +3,12 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"net"
 	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,13 +18,63 @@ import (
 
 var (
 	// HTTP command flags
-	httpLocalPort int
-	httpSubdomain string
-	httpAuthType  string
-	httpUsername  string
-	httpPassword  string
-	httpHeader    string
-	httpValue     string
+	httpLocalPort  int
+	httpSubdomain  string
+	httpHostname   string
+	httpAuthType   string
+	httpUsername   string
+	httpPassword   string
+	httpHeader     string
+	httpValue      string
+	httpHostHeader string
+	httpRoutes     []string
+	httpUpstreams  []string
+	httpLBStrategy string
+	httpLBHeader   string
+	httpHealthPath string
+	httpHealthInt  time.Duration
+	httpScheme     string
+	httpUpstreamCA string
+	httpInsecure   bool
+	httpClientCert string
+	httpClientKey  string
+	httpUpstream   string
+	httpRewriteURL bool
+	httpRewriteHdr bool
+	httpPassHash   string
+	httpOIDCIssuer string
+	httpOIDCClient string
+	httpOIDCSecret string
+	httpOIDCEmails []string
+	httpOIDCDomain []string
+	httpOIDCCookie string
+	httpJWTSecret  string
+	httpJWTPubKey  string
+	httpJWKS       string
+	httpJWTIssuer  string
+	httpJWTAud     string
+	httpJWTClaims  []string
+	httpJWTHeaders []string
+	httpRateLimit  float64
+	httpRateBurst  int
+	httpIPRate     float64
+	httpIPBurst    int
+	httpMaxFlight  int
+	httpVerify     string
+	httpVerifySec  string
+	httpVerifyHdr  string
+	httpVerifyTol  time.Duration
+	httpErrorPages []string
+	httpDialTO     time.Duration
+	httpTLSTO      time.Duration
+	httpRespTO     time.Duration
+	httpIdleTO     time.Duration
+	httpRetries    int
+	httpRetryWait  time.Duration
+	httpMockFile   string
+	httpMaxReqBody string
+	httpMaxResBody string
+	httpProtocol   string
 )
 
 // httpCmd is the command to create an HTTP tunnel
@@ -33,51 +85,87 @@ var httpCmd = &cobra.Command{
 Examples:
   haxor http http://localhost:8080
   haxor http --port 8080 --subdomain myapp
-  haxor http --port 3000 --auth basic --username user --password pass`,
+  haxor http --port 8080 --hostname app.example.com
+  haxor http --port 3000 --auth basic --username user --password pass
+  haxor http --port 3000 --auth oidc --oidc-issuer https://accounts.google.com --oidc-client-id ID --oidc-client-secret SECRET --oidc-allow-domain example.com
+  haxor http --port 3000 --auth jwt --jwks https://issuer.example.com/.well-known/jwks.json --jwt-audience my-api --jwt-claim-header sub=X-User-ID
+  haxor http --port 3000 --host-header preserve
+  haxor http --port 8000 --host-header myapp.local
+  haxor http --port 3000 --route "/api/*=8080,strip"
+  haxor http --upstream localhost:3000 --upstream 192.168.1.10:3000 --lb least_conn --health-path /healthz
+  haxor http https://localhost:8443 --upstream-ca ./dev-ca.pem
+  haxor http unix:///run/app.sock
+  haxor http --port 50051 --protocol h2c
+  haxor http --port 3000 --allow-ip 203.0.113.0/24 --deny-ip 203.0.113.66
+  haxor http --port 3000 --rate-limit 20 --ip-rate-limit 5 --max-in-flight 10
+  haxor http --port 4000 --verify github --verify-secret my-webhook-secret`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check if URL argument is provided
 		if len(args) > 0 {
 			// Parse URL from argument
 			targetURL := args[0]
 
-			// Extract port and host from URL
-			u, err := url.Parse(targetURL)
-			if err != nil {
-				fmt.Printf("Error: URL tidak valid: %v\n", err)
-				os.Exit(1)
-			}
+			// Socket Unix tidak memiliki port
+			if model.UnixSocketPath(targetURL) != "" {
+				httpUpstream = targetURL
+			} else {
+				// Extract port and host from URL
+				u, err := url.Parse(targetURL)
+				if err != nil {
+					fmt.Printf("Error: URL tidak valid: %v\n", err)
+					os.Exit(1)
+				}
 
-			// Extract port from URL
-			port := u.Port()
-			if port == "" {
-				// Default port berdasarkan skema
-				if u.Scheme == "https" {
-					port = "443"
-				} else {
-					port = "80"
+				// Extract port from URL
+				port := u.Port()
+				if port == "" {
+					// Default port berdasarkan skema
+					if u.Scheme == "https" {
+						port = "443"
+					} else {
+						port = "80"
+					}
 				}
-			}
 
-			// Konversi port ke integer
-			portInt, err := strconv.Atoi(port)
-			if err != nil {
-				fmt.Printf("Error: Port tidak valid: %v\n", err)
-				os.Exit(1)
-			}
+				// Konversi port ke integer
+				portInt, err := strconv.Atoi(port)
+				if err != nil {
+					fmt.Printf("Error: Port tidak valid: %v\n", err)
+					os.Exit(1)
+				}
+
+				// Set port dan skema lokal
+				httpLocalPort = portInt
+				switch u.Scheme {
+				case "http", "https":
+					httpScheme = u.Scheme
+				case "":
+				default:
+					fmt.Printf("Error: Skema tidak didukung: %s\n", u.Scheme)
+					os.Exit(1)
+				}
 
-			// Set port lokal
-			httpLocalPort = portInt
+				// Gunakan host dari URL sebagai alamat upstream
+				if u.Hostname() != "" {
+					httpUpstream = fmt.Sprintf("%s://%s", u.Scheme, net.JoinHostPort(u.Hostname(), port))
+				}
+			}
 
 			// Generate subdomain otomatis jika tidak ditentukan
-			if httpSubdomain == "" {
+			if httpSubdomain == "" && httpHostname == "" {
 				// Gunakan timestamp untuk membuat subdomain unik tanpa awalan "haxor-"
 				timestamp := time.Now().UnixNano() / int64(time.Millisecond)
 				httpSubdomain = fmt.Sprintf("%x", timestamp%0xFFFFFF)
 			}
 		}
 
+		// Gunakan port upstream pertama jika port lokal tidak ditentukan
+		if httpLocalPort <= 0 && len(httpUpstreams) > 0 {
+			httpLocalPort = upstreamPort(httpUpstreams[0])
+		}
+
 		// Validasi parameter
-		if httpLocalPort <= 0 {
+		if httpLocalPort <= 0 && httpUpstream == "" {
 			fmt.Println("Error: Port lokal harus lebih besar dari 0")
 			os.Exit(1)
 		}
@@ -91,8 +179,9 @@ Examples:
 				auth.Type = model.AuthTypeBasic
 				auth.Username = httpUsername
 				auth.Password = httpPassword
-				if auth.Username == "" || auth.Password == "" {
-					fmt.Println("Error: Username dan password diperlukan untuk auth basic")
+				auth.PasswordHash = httpPassHash
+				if auth.Username == "" || (auth.Password == "" && auth.PasswordHash == "") {
+					fmt.Println("Error: Username dan password (atau hash password) diperlukan untuk auth basic")
 					os.Exit(1)
 				}
 			case "header":
@@ -103,12 +192,65 @@ Examples:
 					fmt.Println("Error: Nama dan nilai header diperlukan untuk auth header")
 					os.Exit(1)
 				}
+			case "oidc":
+				auth.Type = model.AuthTypeOIDC
+				auth.Issuer = httpOIDCIssuer
+				auth.ClientID = httpOIDCClient
+				auth.ClientSecret = httpOIDCSecret
+				auth.AllowedEmails = httpOIDCEmails
+				auth.AllowedDomains = httpOIDCDomain
+				auth.CookieSecret = httpOIDCCookie
+				if auth.Issuer == "" || auth.ClientID == "" {
+					fmt.Println("Error: Issuer dan client ID diperlukan untuk auth oidc")
+					os.Exit(1)
+				}
+			case "jwt":
+				auth.Type = model.AuthTypeJWT
+				auth.JWTSecret = httpJWTSecret
+				auth.JWTPublicKey = httpJWTPubKey
+				auth.JWKS = httpJWKS
+				auth.Issuer = httpJWTIssuer
+				auth.Audience = httpJWTAud
+				if auth.JWTSecret == "" && auth.JWTPublicKey == "" && auth.JWKS == "" {
+					fmt.Println("Error: Secret, public key atau JWKS diperlukan untuk auth jwt")
+					os.Exit(1)
+				}
+				var err error
+				if auth.RequiredClaims, err = parseKeyValues(httpJWTClaims); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
+				if auth.ClaimHeaders, err = parseKeyValues(httpJWTHeaders); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					os.Exit(1)
+				}
 			default:
 				fmt.Printf("Error: Tipe auth tidak valid: %s\n", httpAuthType)
 				os.Exit(1)
 			}
 		}
 
+		// Parse route tambahan
+		routes, err := parseRoutes(httpRoutes)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Parse halaman error kustom
+		errorPages, err := parseKeyValues(httpErrorPages)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Parse batas ukuran body
+		maxRequestBody, maxResponseBody, err := bodyLimits(httpMaxReqBody, httpMaxResBody)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
 		// Periksa konfigurasi token terlebih dahulu
 		if Container.Config.AuthEnabled {
 			if Container.Config.AuthToken == "" {
@@ -187,11 +329,56 @@ Examples:
 			}
 		}
 
+		// Periksa domain kustom
+		if httpHostname != "" && model.IsCustomDomain(httpHostname, Container.Config.BaseDomain) {
+			if err := Container.Client.CheckCustomDomains(); err != nil {
+				fmt.Println("\n===================================================")
+				fmt.Println("⚠️ ERROR: Domain kustom tidak tersedia")
+				fmt.Println("===================================================")
+				fmt.Printf("Langganan Anda tidak mencakup domain kustom (%s).\n", httpHostname)
+				fmt.Println("\nSaran:")
+				fmt.Println("- Upgrade langganan Anda untuk menggunakan domain kustom")
+				fmt.Println("  https://haxorport.online/pricing")
+				fmt.Println("===================================================")
+				os.Exit(1)
+			}
+			if domain := Container.Config.GetDomain(httpHostname); domain == nil || !domain.Verified {
+				fmt.Fprintf(os.Stderr, "⚠️  Domain %s belum diverifikasi, jalankan 'haxor domain add %s' dan 'haxor domain verify %s'\n", httpHostname, httpHostname, httpHostname)
+			}
+		}
+
 		// Jalankan client dengan reconnect otomatis
 		Container.Client.RunWithReconnect()
 
 		// Buat tunnel
-		tunnel, err := Container.TunnelService.CreateHTTPTunnel(httpLocalPort, httpSubdomain, auth)
+		tunnel, err := Container.TunnelService.CreateHTTPTunnelWithConfig(model.TunnelConfig{
+			LocalPort:       httpLocalPort,
+			Subdomain:       httpSubdomain,
+			Hostname:        httpHostname,
+			Auth:            auth,
+			HostHeader:      httpHostHeader,
+			Routes:          routes,
+			Upstreams:       httpUpstreams,
+			LoadBalance:     model.LoadBalanceStrategy(httpLBStrategy),
+			HashHeader:      httpLBHeader,
+			HealthCheck:     healthCheckConfig(httpHealthPath, httpHealthInt),
+			Scheme:          httpScheme,
+			UpstreamTLS:     upstreamTLSConfig(httpUpstreamCA, httpInsecure, httpClientCert, httpClientKey),
+			Upstream:        httpUpstream,
+			RewriteURLs:     httpRewriteURL,
+			RewriteHeaders:  httpRewriteHdr,
+			IPPolicy:        ipPolicyConfig(),
+			RateLimit:       rateLimitConfig(httpRateLimit, httpRateBurst, httpIPRate, httpIPBurst, httpMaxFlight),
+			Verify:          webhookVerifyConfig(httpVerify, httpVerifySec, httpVerifyHdr, httpVerifyTol),
+			ErrorPages:      errorPages,
+			Timeouts:        upstreamTimeoutConfig(),
+			MockFile:        httpMockFile,
+			Faults:          faultConfig(),
+			MaxRequestBody:  maxRequestBody,
+			MaxResponseBody: maxResponseBody,
+			CORS:            corsConfig(),
+			Protocol:        model.UpstreamProtocol(httpProtocol),
+		})
 		if err != nil {
 			fmt.Printf("Error: Gagal membuat tunnel: %v\n", err)
 			os.Exit(1)
@@ -209,13 +396,56 @@ Examples:
 		fmt.Fprintf(os.Stderr, "✅ TUNNEL CREATED SUCCESSFULLY!\n")
 		fmt.Fprintf(os.Stderr, "=================================================\n")
 		fmt.Fprintf(os.Stderr, "🌐 Tunnel URL: %s\n", tunnel.URL)
-		fmt.Fprintf(os.Stderr, "🔌 Local Port: %d\n", tunnel.Config.LocalPort)
+		if httpUpstream != "" {
+			fmt.Fprintf(os.Stderr, "🔌 Local Target: %s\n", httpUpstream)
+		} else {
+			fmt.Fprintf(os.Stderr, "🔌 Local Port: %d\n", tunnel.Config.LocalPort)
+		}
+		if httpScheme == "https" {
+			fmt.Fprintf(os.Stderr, "🔐 Local Scheme: https\n")
+		}
+		if httpProtocol != "" {
+			fmt.Fprintf(os.Stderr, "🧬 Local Protocol: %s\n", httpProtocol)
+		}
 		fmt.Fprintf(os.Stderr, "🆔 Tunnel ID: %s\n", tunnel.ID)
 
 		// Display additional information
 		if auth != nil {
 			fmt.Fprintf(os.Stderr, "🔒 Authentication: %s\n", auth.Type)
 		}
+		if httpHostHeader != "" {
+			fmt.Fprintf(os.Stderr, "🏷️  Host Header: %s\n", httpHostHeader)
+		}
+		for _, route := range routes {
+			fmt.Fprintf(os.Stderr, "🔀 Route: %s\n", route)
+		}
+		if len(httpUpstreams) > 0 {
+			strategy := tunnel.Config.LoadBalance
+			if strategy == "" {
+				strategy = model.LoadBalanceRoundRobin
+			}
+			fmt.Fprintf(os.Stderr, "⚖️  Upstreams: %s (%s)\n", strings.Join(httpUpstreams, ", "), strategy)
+		}
+		if tunnel.Config.Verify != nil {
+			fmt.Fprintf(os.Stderr, "✍️  Webhook Verification: %s\n", tunnel.Config.Verify.Provider)
+		}
+		if tunnel.Config.RateLimit != nil {
+			fmt.Fprintf(os.Stderr, "🚦 Rate Limit: %s\n", formatRateLimit(tunnel.Config.RateLimit))
+		}
+		if timeouts := tunnel.Config.Timeouts; timeouts != nil && timeouts.Retries > 0 {
+			fmt.Fprintf(os.Stderr, "🔁 Retries: %d (koneksi ditolak, hanya metode idempoten)\n", timeouts.Retries)
+		}
+		if faults := tunnel.Config.Faults; faults != nil {
+			fmt.Fprintf(os.Stderr, "💥 Fault Injection: %s\n", formatFaults(faults))
+		}
+		if tunnel.Config.CORS != nil {
+			fmt.Fprintf(os.Stderr, "🌍 CORS: %s\n", formatCORS(tunnel.Config.CORS))
+		}
+		if httpMockFile != "" {
+			fmt.Fprintf(os.Stderr, "🎭 Mock Rules: %s (dimuat ulang otomatis saat berubah)\n", httpMockFile)
+		}
+		stopAdmin := startAdminAPI()
+		defer stopAdmin()
 		// Server information is not displayed
 		fmt.Fprintf(os.Stderr, "📝 Log File: %s\n", Container.Config.LogFile)
 
@@ -239,6 +469,11 @@ Examples:
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
 
+		// Tampilkan statistik permintaan tunnel
+		if stats, ok := Container.Client.TunnelStats(tunnel.ID); ok {
+			fmt.Printf("📊 Requests: %d, rate limited: %d, peak in-flight: %d\n", stats.Requests, stats.RateLimited, stats.PeakInFlight)
+		}
+
 		// Close tunnel
 		if err := Container.TunnelService.CloseTunnel(tunnel.ID); err != nil {
 			fmt.Printf("Error: Failed to close tunnel: %v\n", err)
@@ -254,12 +489,231 @@ func init() {
 	// Tambahkan flag
 	httpCmd.Flags().IntVarP(&httpLocalPort, "port", "p", 0, "Port lokal yang akan di-tunnel")
 	httpCmd.Flags().StringVarP(&httpSubdomain, "subdomain", "s", "", "Subdomain yang diminta (opsional)")
-	httpCmd.Flags().StringVarP(&httpAuthType, "auth", "a", "", "Tipe autentikasi (basic, header)")
+	httpCmd.Flags().StringVar(&httpHostname, "hostname", "", "Domain kustom untuk tunnel, misal app.example.com (lihat 'haxor domain add')")
+	httpCmd.Flags().StringVarP(&httpAuthType, "auth", "a", "", "Tipe autentikasi (basic, header, oidc, jwt)")
 	httpCmd.Flags().StringVarP(&httpUsername, "username", "u", "", "Username untuk autentikasi basic")
 	httpCmd.Flags().StringVarP(&httpPassword, "password", "w", "", "Password untuk autentikasi basic")
+	httpCmd.Flags().StringVar(&httpPassHash, "password-hash", "", "Hash bcrypt password untuk autentikasi basic (lihat 'haxor config hash-password')")
 	httpCmd.Flags().StringVar(&httpHeader, "header", "", "Nama header untuk autentikasi header")
 	httpCmd.Flags().StringVar(&httpValue, "value", "", "Nilai header untuk autentikasi header")
+	httpCmd.Flags().StringVar(&httpOIDCIssuer, "oidc-issuer", "", "URL issuer OpenID Connect untuk autentikasi oidc")
+	httpCmd.Flags().StringVar(&httpOIDCClient, "oidc-client-id", "", "Client ID OpenID Connect")
+	httpCmd.Flags().StringVar(&httpOIDCSecret, "oidc-client-secret", "", "Client secret OpenID Connect")
+	httpCmd.Flags().StringArrayVar(&httpOIDCEmails, "oidc-allow-email", nil, "Email yang diizinkan login (dapat diulang)")
+	httpCmd.Flags().StringArrayVar(&httpOIDCDomain, "oidc-allow-domain", nil, "Domain email yang diizinkan login (dapat diulang)")
+	httpCmd.Flags().StringVar(&httpOIDCCookie, "oidc-cookie-secret", "", "Secret untuk menandatangani cookie sesi (sesi tetap berlaku setelah restart)")
+	httpCmd.Flags().StringVar(&httpJWTSecret, "jwt-secret", "", "Secret HMAC untuk verifikasi token JWT (HS256/384/512)")
+	httpCmd.Flags().StringVar(&httpJWTPubKey, "jwt-public-key", "", "File public key atau sertifikat (PEM) untuk verifikasi token JWT")
+	httpCmd.Flags().StringVar(&httpJWKS, "jwks", "", "File atau URL JWKS untuk verifikasi token JWT")
+	httpCmd.Flags().StringVar(&httpJWTIssuer, "jwt-issuer", "", "Nilai klaim iss yang wajib")
+	httpCmd.Flags().StringVar(&httpJWTAud, "jwt-audience", "", "Nilai klaim aud yang wajib")
+	httpCmd.Flags().StringArrayVar(&httpJWTClaims, "jwt-claim", nil, "Klaim wajib, format NAMA=NILAI atau NAMA (dapat diulang)")
+	httpCmd.Flags().StringArrayVar(&httpJWTHeaders, "jwt-claim-header", nil, "Teruskan klaim sebagai header, format KLAIM=HEADER (dapat diulang)")
+	httpCmd.Flags().StringVar(&httpHostHeader, "host-header", "", "Header Host ke layanan lokal (rewrite, preserve, atau nilai kustom; default rewrite)")
+	httpCmd.Flags().StringArrayVar(&httpRoutes, "route", nil, "Route path ke layanan lokal lain, format PATTERN=TARGET[,strip] (dapat diulang)")
+	httpCmd.Flags().StringArrayVar(&httpUpstreams, "upstream", nil, "Alamat upstream untuk load balancing, misal localhost:3000 (dapat diulang)")
+	httpCmd.Flags().StringVar(&httpLBStrategy, "lb", "", "Strategi load balancing (round_robin, least_conn, hash)")
+	httpCmd.Flags().StringVar(&httpLBHeader, "lb-header", "", "Header untuk strategi load balancing hash")
+	httpCmd.Flags().StringVar(&httpHealthPath, "health-path", "", "Path health check HTTP untuk upstream (kosong untuk cek TCP)")
+	httpCmd.Flags().DurationVar(&httpHealthInt, "health-interval", 0, "Interval health check upstream (mengaktifkan health check)")
+	httpCmd.Flags().StringVar(&httpUpstreamCA, "upstream-ca", "", "File CA (PEM) untuk verifikasi layanan lokal HTTPS")
+	httpCmd.Flags().BoolVar(&httpInsecure, "upstream-insecure", false, "Lewati verifikasi sertifikat layanan lokal HTTPS")
+	httpCmd.Flags().StringVar(&httpClientCert, "upstream-cert", "", "Sertifikat klien (PEM) untuk layanan lokal HTTPS")
+	httpCmd.Flags().StringVar(&httpClientKey, "upstream-key", "", "Kunci sertifikat klien (PEM) untuk layanan lokal HTTPS")
+	httpCmd.Flags().StringVar(&httpProtocol, "protocol", "", "Protokol ke layanan lokal (auto, http1, h2c untuk gRPC tanpa TLS)")
+	httpCmd.Flags().BoolVar(&httpRewriteURL, "rewrite-urls", false, "Ganti URL lokal dalam respons HTML/CSS dengan URL tunnel")
+	httpCmd.Flags().BoolVar(&httpRewriteHdr, "rewrite-headers", false, "Sesuaikan header Location, Refresh dan Set-Cookie dengan URL tunnel")
+
+	httpCmd.Flags().Float64Var(&httpRateLimit, "rate-limit", 0, "Batas permintaan per detik untuk seluruh tunnel (0 untuk tanpa batas)")
+	httpCmd.Flags().IntVar(&httpRateBurst, "rate-burst", 0, "Jumlah permintaan sekaligus di atas batas laju tunnel")
+	httpCmd.Flags().Float64Var(&httpIPRate, "ip-rate-limit", 0, "Batas permintaan per detik untuk setiap IP pengunjung (0 untuk tanpa batas)")
+	httpCmd.Flags().IntVar(&httpIPBurst, "ip-rate-burst", 0, "Jumlah permintaan sekaligus di atas batas laju per IP")
+	httpCmd.Flags().IntVar(&httpMaxFlight, "max-in-flight", 0, "Jumlah maksimum permintaan bersamaan ke layanan lokal (0 untuk tanpa batas)")
+	httpCmd.Flags().StringVar(&httpVerify, "verify", "", "Verifikasi tanda tangan webhook (github, stripe, slack, twilio, shopify, generic)")
+	httpCmd.Flags().StringVar(&httpVerifySec, "verify-secret", "", "Secret penandatanganan webhook (auth token untuk twilio)")
+	httpCmd.Flags().StringVar(&httpVerifyHdr, "verify-header", "", "Header tanda tangan untuk verifikasi webhook generic")
+	httpCmd.Flags().DurationVar(&httpVerifyTol, "verify-tolerance", 0, "Toleransi umur timestamp tanda tangan webhook (default 5m)")
+	httpCmd.Flags().StringArrayVar(&httpErrorPages, "error-page", nil, "Template halaman error kustom dalam format KODE=FILE, misalnya 502=./502.html atau 5xx=./error.html (dapat diulang)")
+	addUpstreamTimeoutFlags(httpCmd)
+	addFaultFlags(httpCmd, true)
+	httpCmd.Flags().StringVar(&httpMaxReqBody, "max-request-body", "", "Ukuran maksimum body permintaan, misalnya 10MB (default 100MB)")
+	httpCmd.Flags().StringVar(&httpMaxResBody, "max-response-body", "", "Ukuran maksimum body respons layanan lokal, misalnya 50MB (default 100MB)")
+	httpCmd.Flags().StringVar(&httpMockFile, "mock-file", "", "File YAML berisi aturan mock yang dijawab langsung oleh client")
+	addIPPolicyFlags(httpCmd)
+	addCORSFlags(httpCmd)
 
 	// Port hanya wajib jika URL tidak diberikan
 	// httpCmd.MarkFlagRequired("port")
 }
+
+// parseRoutes mengurai nilai flag --route
+func parseRoutes(specs []string) ([]model.TunnelRoute, error) {
+	routes := make([]model.TunnelRoute, 0, len(specs))
+	for _, spec := range specs {
+		route, err := model.ParseTunnelRoute(spec)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// bodyLimits mengurai flag batas ukuran body permintaan dan respons
+func bodyLimits(request string, response string) (int64, int64, error) {
+	maxRequest, err := parseByteSize(request)
+	if err != nil {
+		return 0, 0, fmt.Errorf("--max-request-body tidak valid: %v", err)
+	}
+	maxResponse, err := parseByteSize(response)
+	if err != nil {
+		return 0, 0, fmt.Errorf("--max-response-body tidak valid: %v", err)
+	}
+	return maxRequest, maxResponse, nil
+}
+
+// parseByteSize mengurai ukuran seperti 512KB, 10MB atau 1GB (kelipatan 1024); string kosong berarti 0
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("ukuran tidak valid: %s", value)
+	}
+	return int64(size * float64(multiplier)), nil
+}
+
+// parseKeyValues mengurai nilai flag berformat NAMA=NILAI; NAMA saja berarti nilai kosong
+func parseKeyValues(specs []string) (map[string]string, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		key, value, _ := strings.Cut(spec, "=")
+		if key == "" {
+			return nil, fmt.Errorf("format tidak valid: %s", spec)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// upstreamPort mengembalikan port dari alamat upstream, atau 0 jika tidak valid
+func upstreamPort(addr string) int {
+	_, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		portStr = strings.TrimPrefix(addr, ":")
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return 0
+	}
+	return port
+}
+
+// healthCheckConfig membuat konfigurasi health check dari flag, atau nil jika tidak diaktifkan
+func healthCheckConfig(path string, interval time.Duration) *model.HealthCheckConfig {
+	if path == "" && interval <= 0 {
+		return nil
+	}
+	return &model.HealthCheckConfig{
+		Path:     path,
+		Interval: interval,
+	}
+}
+
+// rateLimitConfig membuat konfigurasi batas laju dari flag, atau nil jika tidak ditentukan
+func rateLimitConfig(rate float64, burst int, ipRate float64, ipBurst int, maxInFlight int) *model.RateLimitConfig {
+	if rate <= 0 && ipRate <= 0 && maxInFlight <= 0 {
+		return nil
+	}
+	return &model.RateLimitConfig{
+		Rate:        rate,
+		Burst:       burst,
+		PerIPRate:   ipRate,
+		PerIPBurst:  ipBurst,
+		MaxInFlight: maxInFlight,
+	}
+}
+
+// addUpstreamTimeoutFlags menambahkan flag timeout dan retry layanan lokal ke perintah
+func addUpstreamTimeoutFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&httpDialTO, "dial-timeout", 0, "Batas waktu koneksi ke layanan lokal (default 10s)")
+	cmd.Flags().DurationVar(&httpTLSTO, "tls-timeout", 0, "Batas waktu handshake TLS ke layanan lokal HTTPS (default 10s)")
+	cmd.Flags().DurationVar(&httpRespTO, "response-timeout", 0, "Batas waktu menunggu header respons dari layanan lokal (default 60s)")
+	cmd.Flags().DurationVar(&httpIdleTO, "idle-timeout", 0, "Lama koneksi keep-alive yang menganggur tetap dibuka (default 90s)")
+	cmd.Flags().IntVar(&httpRetries, "retries", 0, "Jumlah percobaan ulang metode idempoten saat layanan lokal menolak koneksi")
+	cmd.Flags().DurationVar(&httpRetryWait, "retry-backoff", 0, "Jeda sebelum percobaan ulang pertama, digandakan setiap percobaan (default 250ms)")
+}
+
+// upstreamTimeoutConfig membuat konfigurasi timeout dan retry dari flag, atau nil jika tidak ditentukan
+func upstreamTimeoutConfig() *model.UpstreamTimeoutConfig {
+	if httpDialTO == 0 && httpTLSTO == 0 && httpRespTO == 0 && httpIdleTO == 0 && httpRetries == 0 && httpRetryWait == 0 {
+		return nil
+	}
+	return &model.UpstreamTimeoutConfig{
+		Dial:           httpDialTO,
+		TLSHandshake:   httpTLSTO,
+		ResponseHeader: httpRespTO,
+		Idle:           httpIdleTO,
+		Retries:        httpRetries,
+		RetryBackoff:   httpRetryWait,
+	}
+}
+
+// webhookVerifyConfig membuat konfigurasi verifikasi webhook dari flag, atau nil jika tidak ditentukan
+func webhookVerifyConfig(provider string, secret string, header string, tolerance time.Duration) *model.WebhookVerifyConfig {
+	if provider == "" {
+		return nil
+	}
+	return &model.WebhookVerifyConfig{
+		Provider:  model.WebhookProvider(provider),
+		Secret:    secret,
+		Header:    header,
+		Tolerance: tolerance,
+	}
+}
+
+// formatRateLimit menampilkan konfigurasi batas laju dalam satu baris
+func formatRateLimit(config *model.RateLimitConfig) string {
+	var parts []string
+	if config.Rate > 0 {
+		parts = append(parts, fmt.Sprintf("%g req/s per tunnel", config.Rate))
+	}
+	if config.PerIPRate > 0 {
+		parts = append(parts, fmt.Sprintf("%g req/s per IP", config.PerIPRate))
+	}
+	if config.MaxInFlight > 0 {
+		parts = append(parts, fmt.Sprintf("max %d in flight", config.MaxInFlight))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// upstreamTLSConfig membuat konfigurasi TLS upstream dari flag, atau nil jika tidak ditentukan
+func upstreamTLSConfig(caFile string, insecure bool, certFile string, keyFile string) *model.UpstreamTLSConfig {
+	if caFile == "" && !insecure && certFile == "" && keyFile == "" {
+		return nil
+	}
+	return &model.UpstreamTLSConfig{
+		CAFile:             caFile,
+		InsecureSkipVerify: insecure,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// IP policy flags, shared by the http, tcp and config add-tunnel commands
+	ipAllow   []string
+	ipDeny    []string
+	ipGeoIPDB string
+	ipAllowCC []string
+	ipDenyCC  []string
+)
+
+// addIPPolicyFlags menambahkan flag kebijakan IP ke sebuah perintah
+func addIPPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&ipAllow, "allow-ip", nil, "Rentang CIDR atau IP yang diizinkan mengakses tunnel (dapat diulang)")
+	cmd.Flags().StringArrayVar(&ipDeny, "deny-ip", nil, "Rentang CIDR atau IP yang selalu ditolak (dapat diulang)")
+	cmd.Flags().StringVar(&ipGeoIPDB, "geoip-db", "", "File database GeoIP MaxMind (MMDB) untuk aturan negara")
+	cmd.Flags().StringArrayVar(&ipAllowCC, "allow-country", nil, "Kode negara ISO yang diizinkan, butuh --geoip-db (dapat diulang)")
+	cmd.Flags().StringArrayVar(&ipDenyCC, "deny-country", nil, "Kode negara ISO yang selalu ditolak, butuh --geoip-db (dapat diulang)")
+}
+
+// ipPolicyConfig membuat kebijakan IP dari flag, atau nil jika tidak ditentukan
+func ipPolicyConfig() *model.IPPolicyConfig {
+	if len(ipAllow) == 0 && len(ipDeny) == 0 && len(ipAllowCC) == 0 && len(ipDenyCC) == 0 {
+		return nil
+	}
+	return &model.IPPolicyConfig{
+		Allow:          ipAllow,
+		Deny:           ipDeny,
+		GeoIPDatabase:  ipGeoIPDB,
+		AllowCountries: ipAllowCC,
+		DenyCountries:  ipDenyCC,
+	}
+}
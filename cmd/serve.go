@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/fileserver"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveSubdomain string
+	serveUsername  string
+	servePassword  string
+	serveSPA       bool
+	serveNoListing bool
+)
+
+// serveCmd is the command to share a directory through an HTTP tunnel
+var serveCmd = &cobra.Command{
+	Use:   "serve [dir]",
+	Short: "Share a directory through an HTTP tunnel",
+	Long: `Share a directory through an HTTP tunnel without running a local server.
+Files are served by a file server inside the client, with directory listing
+and range requests. Dot files such as .git and .env are never served.
+Examples:
+  haxor serve ./dist --spa
+  haxor serve . --subdomain files
+  haxor serve ./build --username admin --password s3cret --no-listing`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		var auth *model.TunnelAuth
+		if serveUsername != "" || servePassword != "" {
+			if serveUsername == "" || servePassword == "" {
+				fmt.Println("Error: Username dan password diperlukan untuk auth basic")
+				os.Exit(1)
+			}
+			auth = &model.TunnelAuth{
+				Type:     model.AuthTypeBasic,
+				Username: serveUsername,
+				Password: servePassword,
+			}
+		}
+
+		server, err := fileserver.New(dir, fileserver.Options{
+			Listing: !serveNoListing,
+			SPA:     serveSPA,
+		})
+		if err != nil {
+			fmt.Printf("Error: Direktori tidak valid: %v\n", err)
+			os.Exit(1)
+		}
+
+		localPort, err := server.Start()
+		if err != nil {
+			fmt.Printf("Error: Gagal menjalankan file server: %v\n", err)
+			os.Exit(1)
+		}
+		defer server.Close()
+
+		if !Container.Client.IsConnected() {
+			if err := Container.Client.Connect(); err != nil {
+				fmt.Printf("Error: Failed to connect to server: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if Container.Config.AuthEnabled {
+			userData := Container.Client.GetUserData()
+			if userData == nil {
+				fmt.Println("Error: Invalid or unvalidated authentication token")
+				os.Exit(1)
+			}
+
+			reached, used, limit := Container.Client.CheckTunnelLimit()
+			if reached {
+				fmt.Printf("Error: Tunnel limit reached (%d/%d). Please upgrade your subscription.\n", used, limit)
+				os.Exit(1)
+			}
+		}
+
+		Container.Client.RunWithReconnect()
+
+		tunnel, err := Container.TunnelService.CreateHTTPTunnel(localPort, serveSubdomain, auth)
+		if err != nil {
+			fmt.Printf("Error: Gagal membuat tunnel: %v\n", err)
+			os.Exit(1)
+		}
+
+		log.Printf("Serving %s on local port %d", server.Root(), localPort)
+
+		fmt.Fprintf(os.Stderr, "\n=================================================\n")
+		fmt.Fprintf(os.Stderr, "✅ TUNNEL CREATED SUCCESSFULLY!\n")
+		fmt.Fprintf(os.Stderr, "=================================================\n")
+		fmt.Fprintf(os.Stderr, "🌐 Tunnel URL: %s\n", tunnel.URL)
+		fmt.Fprintf(os.Stderr, "📁 Directory: %s\n", server.Root())
+		if serveSPA {
+			fmt.Fprintf(os.Stderr, "🧭 SPA Fallback: index.html\n")
+		}
+		if serveNoListing {
+			fmt.Fprintf(os.Stderr, "🙈 Directory Listing: disabled\n")
+		}
+		if auth != nil {
+			fmt.Fprintf(os.Stderr, "🔒 Authentication: %s\n", auth.Type)
+		}
+		fmt.Fprintf(os.Stderr, "🆔 Tunnel ID: %s\n", tunnel.ID)
+		stopAdmin := startAdminAPI()
+		defer stopAdmin()
+		fmt.Fprintf(os.Stderr, "=================================================\n")
+		fmt.Fprintf(os.Stderr, "📋 Press Ctrl+C to stop the tunnel\n")
+		fmt.Fprintf(os.Stderr, "=================================================\n")
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+
+		if err := Container.TunnelService.CloseTunnel(tunnel.ID); err != nil {
+			fmt.Printf("Error: Failed to close tunnel: %v\n", err)
+		} else {
+			fmt.Println("Tunnel closed")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVarP(&serveSubdomain, "subdomain", "s", "", "Subdomain yang diminta (opsional)")
+	serveCmd.Flags().StringVarP(&serveUsername, "username", "u", "", "Username untuk autentikasi basic (opsional)")
+	serveCmd.Flags().StringVarP(&servePassword, "password", "w", "", "Password untuk autentikasi basic (opsional)")
+	serveCmd.Flags().BoolVar(&serveSPA, "spa", false, "Kirim index.html untuk path yang tidak ada (aplikasi single page)")
+	serveCmd.Flags().BoolVar(&serveNoListing, "no-listing", false, "Nonaktifkan daftar isi direktori")
+}
@@ -25,9 +25,13 @@ var tcpCmd = &cobra.Command{
 	Long: `Create a TCP tunnel to expose local TCP services to the internet.
 Examples:
   haxor tcp --port 22 --remote-port 2222
-  haxor tcp --port 5432`,
+  haxor tcp --port 5432
+  haxor tcp --local-addr unix:/var/run/docker.sock
+  haxor tcp --port 5432 --allow-ip 10.8.0.0/16`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if tcpLocalPort <= 0 {
+		unixSocket := model.UnixSocketPath(tcpLocalAddr) != ""
+
+		if tcpLocalPort <= 0 && !unixSocket {
 			fmt.Println("Error: Local port must be greater than 0")
 			os.Exit(1)
 		}
@@ -58,8 +62,11 @@ Examples:
 		localHost := "127.0.0.1"
 		localPort := tcpLocalPort
 
+		var upstream string
 		host, _, err := net.SplitHostPort(tcpLocalAddr)
-		if err == nil {
+		if unixSocket {
+			upstream = tcpLocalAddr
+		} else if err == nil {
 			if host != "" {
 				localHost = host
 			}
@@ -75,6 +82,9 @@ Examples:
 			LocalAddr:  localHost,
 			LocalPort:  localPort,
 			RemotePort: tcpRemotePort,
+			Upstream:   upstream,
+			IPPolicy:   ipPolicyConfig(),
+			Faults:     faultConfig(),
 		}
 
 		tunnel, err := Container.TunnelService.CreateTCPTunnel(tunnelConfig)
@@ -87,7 +97,18 @@ Examples:
 
 		fmt.Printf("TCP tunnel created successfully!\n")
 		fmt.Printf("Remote Port: %d\n", tunnel.RemotePort)
-		fmt.Printf("Local Port: %d\n", tunnel.Config.LocalPort)
+		if unixSocket {
+			fmt.Printf("Local Socket: %s\n", model.UnixSocketPath(upstream))
+		} else {
+			fmt.Printf("Local Port: %d\n", tunnel.Config.LocalPort)
+		}
+
+		if tunnel.Config.Faults != nil {
+			fmt.Printf("Fault Injection: %s\n", formatFaults(tunnel.Config.Faults))
+		}
+
+		stopAdmin := startAdminAPI()
+		defer stopAdmin()
 
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -106,7 +127,7 @@ func init() {
 
 	tcpCmd.Flags().IntVarP(&tcpLocalPort, "port", "p", 0, "Local port to tunnel")
 	tcpCmd.Flags().IntVarP(&tcpRemotePort, "remote-port", "r", 0, "Requested remote port (optional)")
-	tcpCmd.Flags().StringVarP(&tcpLocalAddr, "local-addr", "l", "127.0.0.1", "Local address to forward to (default: 127.0.0.1)")
-
-	tcpCmd.MarkFlagRequired("port")
+	tcpCmd.Flags().StringVarP(&tcpLocalAddr, "local-addr", "l", "127.0.0.1", "Local address to forward to, or unix:/path for a Unix socket (default: 127.0.0.1)")
+	addIPPolicyFlags(tcpCmd)
+	addFaultFlags(tcpCmd, false)
 }
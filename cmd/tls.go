@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tlsLocalPort int
+	tlsLocalAddr string
+	tlsHostname  string
+	tlsCertFiles []string
+	tlsKeyFiles  []string
+)
+
+// tlsCmd is the command to create a TLS passthrough tunnel
+var tlsCmd = &cobra.Command{
+	Use:   "tls",
+	Short: "Create a TLS passthrough tunnel",
+	Long: `Create a TLS passthrough tunnel to a local TLS server.
+Connections are routed by their SNI hostname and forwarded without being
+decrypted, so certificates and mutual TLS stay end-to-end.
+With --cert and --key the client terminates TLS itself with your own
+certificates and proxies the decrypted requests to a local HTTP service;
+the relay still only sees encrypted traffic. Certificates are reloaded when
+their files change, and the one matching the SNI hostname is served.
+Examples:
+  haxor tls --port 8443 --hostname foo.example
+  haxor tls --port 443 --hostname "*.dev.example.com"
+  haxor tls --port 8080 --hostname app.example.com --cert app.pem --key app-key.pem`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if tlsLocalPort <= 0 {
+			fmt.Println("Error: Local port must be greater than 0")
+			os.Exit(1)
+		}
+		if tlsHostname == "" {
+			fmt.Println("Error: Hostname is required for TLS tunnels")
+			os.Exit(1)
+		}
+
+		termination, err := tlsTerminationConfig()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !Container.Client.IsConnected() {
+			if err := Container.Client.Connect(); err != nil {
+				fmt.Printf("Error: Failed to connect to server: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if Container.Config.AuthEnabled {
+			userData := Container.Client.GetUserData()
+			if userData == nil {
+				fmt.Println("Error: Invalid or unvalidated authentication token")
+				os.Exit(1)
+			}
+
+			reached, used, limit := Container.Client.CheckTunnelLimit()
+			if reached {
+				fmt.Printf("Error: Tunnel limit reached (%d/%d). Please upgrade your subscription.\n", used, limit)
+				os.Exit(1)
+			}
+		}
+
+		Container.Client.RunWithReconnect()
+
+		tunnelConfig := model.TunnelConfig{
+			Type:      model.TunnelTypeTLS,
+			LocalAddr: tlsLocalAddr,
+			LocalPort: tlsLocalPort,
+			Hostname:  tlsHostname,
+			Faults:    faultConfig(),
+
+			TLSTermination: termination,
+		}
+
+		tunnel, err := Container.TunnelService.CreateTLSTunnel(tunnelConfig)
+		if err != nil {
+			fmt.Printf("Error: Failed to create tunnel: %v\n", err)
+			os.Exit(1)
+		}
+
+		log.Printf("Creating TLS tunnel for %s to %s:%d", tunnelConfig.Hostname, tunnelConfig.LocalAddr, tunnelConfig.LocalPort)
+
+		fmt.Printf("TLS tunnel created successfully!\n")
+		fmt.Printf("Hostname: %s\n", tunnel.Config.Hostname)
+		if tunnel.URL != "" {
+			fmt.Printf("URL: %s\n", tunnel.URL)
+		}
+		fmt.Printf("Local Port: %d\n", tunnel.Config.LocalPort)
+		fmt.Printf("TLS: %s\n", formatTLSTermination(tunnel.Config.TLSTermination))
+
+		if tunnel.Config.Faults != nil {
+			fmt.Printf("Fault Injection: %s\n", formatFaults(tunnel.Config.Faults))
+		}
+
+		stopAdmin := startAdminAPI()
+		defer stopAdmin()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+
+		if err := Container.TunnelService.CloseTunnel(tunnel.ID); err != nil {
+			fmt.Printf("Error: Failed to close tunnel: %v\n", err)
+		} else {
+			fmt.Println("Tunnel closed")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(tlsCmd)
+
+	tlsCmd.Flags().IntVarP(&tlsLocalPort, "port", "p", 0, "Local port of the TLS server")
+	tlsCmd.Flags().StringVarP(&tlsLocalAddr, "local-addr", "l", "127.0.0.1", "Local address of the TLS server")
+	tlsCmd.Flags().StringVar(&tlsHostname, "hostname", "", "Hostname routed to the tunnel by SNI, e.g. foo.example or *.dev.example.com")
+	tlsCmd.Flags().StringArrayVar(&tlsCertFiles, "cert", nil, "Terminate TLS on the client with this certificate file (PEM, repeatable)")
+	tlsCmd.Flags().StringArrayVar(&tlsKeyFiles, "key", nil, "Private key file (PEM) of the certificate with the same position")
+	addFaultFlags(tlsCmd, false)
+}
+
+// tlsTerminationConfig builds the TLS termination configuration from the
+// --cert and --key flags, or nil for passthrough
+func tlsTerminationConfig() (*model.TLSTerminationConfig, error) {
+	if len(tlsCertFiles) != len(tlsKeyFiles) {
+		return nil, fmt.Errorf("every --cert requires a --key")
+	}
+	if len(tlsCertFiles) == 0 {
+		return nil, nil
+	}
+
+	config := &model.TLSTerminationConfig{}
+	for i := range tlsCertFiles {
+		config.Certificates = append(config.Certificates, model.TLSCertificateFiles{
+			CertFile: tlsCertFiles[i],
+			KeyFile:  tlsKeyFiles[i],
+		})
+	}
+	return config, nil
+}
+
+// formatTLSTermination describes where the TLS of a tunnel is terminated
+func formatTLSTermination(config *model.TLSTerminationConfig) string {
+	if config == nil {
+		return "passthrough to the local server"
+	}
+	return fmt.Sprintf("terminated on the client (%d certificates)", len(config.Certificates))
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/spf13/cobra"
+)
+
+var (
+	udpLocalPort   int
+	udpRemotePort  int
+	udpLocalAddr   string
+	udpIdleTimeout time.Duration
+	udpMaxSessions int
+)
+
+// udpCmd is the command to create a UDP tunnel
+var udpCmd = &cobra.Command{
+	Use:   "udp",
+	Short: "Create a UDP tunnel",
+	Long: `Create a UDP tunnel to expose local UDP services such as game servers,
+DNS resolvers and WireGuard endpoints to the internet.
+Every remote peer gets its own session, which ends after it has been idle.
+Examples:
+  haxor udp --port 51820
+  haxor udp --port 53 --remote-port 5353
+  haxor udp --port 27015 --idle-timeout 5m --allow-ip 203.0.113.0/24`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if udpLocalPort <= 0 {
+			fmt.Println("Error: Local port must be greater than 0")
+			os.Exit(1)
+		}
+
+		if !Container.Client.IsConnected() {
+			if err := Container.Client.Connect(); err != nil {
+				fmt.Printf("Error: Failed to connect to server: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if Container.Config.AuthEnabled {
+			userData := Container.Client.GetUserData()
+			if userData == nil {
+				fmt.Println("Error: Invalid or unvalidated authentication token")
+				os.Exit(1)
+			}
+
+			reached, used, limit := Container.Client.CheckTunnelLimit()
+			if reached {
+				fmt.Printf("Error: Tunnel limit reached (%d/%d). Please upgrade your subscription.\n", used, limit)
+				os.Exit(1)
+			}
+		}
+
+		Container.Client.RunWithReconnect()
+
+		tunnelConfig := model.TunnelConfig{
+			Type:       model.TunnelTypeUDP,
+			LocalAddr:  udpLocalAddr,
+			LocalPort:  udpLocalPort,
+			RemotePort: udpRemotePort,
+			IPPolicy:   ipPolicyConfig(),
+			UDP:        udpConfig(udpIdleTimeout, udpMaxSessions),
+		}
+
+		tunnel, err := Container.TunnelService.CreateUDPTunnel(tunnelConfig)
+		if err != nil {
+			fmt.Printf("Error: Failed to create tunnel: %v\n", err)
+			os.Exit(1)
+		}
+
+		log.Printf("Creating UDP tunnel for %s:%d with remote port %d", tunnelConfig.LocalAddr, tunnelConfig.LocalPort, tunnelConfig.RemotePort)
+
+		fmt.Printf("UDP tunnel created successfully!\n")
+		fmt.Printf("Remote Port: %d\n", tunnel.RemotePort)
+		fmt.Printf("Local Port: %d\n", tunnel.Config.LocalPort)
+		if udpIdleTimeout > 0 {
+			fmt.Printf("Session Idle Timeout: %s\n", udpIdleTimeout)
+		}
+
+		stopAdmin := startAdminAPI()
+		defer stopAdmin()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+
+		if err := Container.TunnelService.CloseTunnel(tunnel.ID); err != nil {
+			fmt.Printf("Error: Failed to close tunnel: %v\n", err)
+		} else {
+			fmt.Println("Tunnel closed")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(udpCmd)
+
+	udpCmd.Flags().IntVarP(&udpLocalPort, "port", "p", 0, "Local UDP port to tunnel")
+	udpCmd.Flags().IntVarP(&udpRemotePort, "remote-port", "r", 0, "Requested remote port (optional)")
+	udpCmd.Flags().StringVarP(&udpLocalAddr, "local-addr", "l", "127.0.0.1", "Local address to forward datagrams to")
+	udpCmd.Flags().DurationVar(&udpIdleTimeout, "idle-timeout", 0, "Close peer sessions without datagrams for this long (default 60s)")
+	udpCmd.Flags().IntVar(&udpMaxSessions, "max-sessions", 0, "Maximum number of concurrent peer sessions (default 1024)")
+	addIPPolicyFlags(udpCmd)
+}
+
+// udpConfig creates the session settings of a UDP tunnel, or nil when the defaults are used
+func udpConfig(idleTimeout time.Duration, maxSessions int) *model.UDPConfig {
+	if idleTimeout == 0 && maxSessions == 0 {
+		return nil
+	}
+	return &model.UDPConfig{
+		IdleTimeout: idleTimeout,
+		MaxSessions: maxSessions,
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+)
+
+// DomainService is a service for managing custom domains
+type DomainService struct {
+	logger port.Logger
+
+	lookupCNAME func(host string) (string, error)
+	lookupTXT   func(name string) ([]string, error)
+	lookupHost  func(host string) ([]string, error)
+}
+
+// NewDomainService creates a new DomainService instance
+func NewDomainService(logger port.Logger) *DomainService {
+	return &DomainService{
+		logger:      logger,
+		lookupCNAME: net.LookupCNAME,
+		lookupTXT:   net.LookupTXT,
+		lookupHost:  net.LookupHost,
+	}
+}
+
+// NewDomain creates a custom domain with a new verification token
+func (s *DomainService) NewDomain(name string) (model.CustomDomain, error) {
+	name = model.NormalizeHostname(name)
+	if err := model.ValidateDomainName(name); err != nil {
+		return model.CustomDomain{}, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return model.CustomDomain{}, fmt.Errorf("failed to generate verification token: %v", err)
+	}
+
+	return model.CustomDomain{Name: name, Token: hex.EncodeToString(token)}, nil
+}
+
+// Verify checks the DNS records of a custom domain: a CNAME to the base
+// domain of the server, or the same addresses for apex domains that use
+// ALIAS/ANAME records, and a TXT record with the token of the domain
+func (s *DomainService) Verify(domain model.CustomDomain, baseDomain string) model.DomainVerification {
+	target := model.NormalizeHostname(baseDomain)
+	result := model.DomainVerification{Target: target}
+
+	s.logger.Info("Verifying custom domain %s", domain.Name)
+
+	cname, err := s.lookupCNAME(domain.Name)
+	switch {
+	case err != nil:
+		result.RouteError = fmt.Sprintf("failed to look up %s: %v", domain.Name, err)
+	case model.NormalizeHostname(cname) == target || strings.HasSuffix(model.NormalizeHostname(cname), "."+target):
+		result.Routed = true
+	case model.NormalizeHostname(cname) != domain.Name:
+		result.RouteError = fmt.Sprintf("%s is a CNAME of %s instead of %s", domain.Name, model.NormalizeHostname(cname), target)
+	default:
+		// No CNAME; apex domains may be flattened to the addresses of the target
+		result.Routed, result.RouteError = s.sameAddresses(domain.Name, target)
+	}
+
+	records, err := s.lookupTXT(domain.VerificationRecord())
+	if err != nil {
+		result.OwnerError = fmt.Sprintf("failed to look up TXT record %s: %v", domain.VerificationRecord(), err)
+		return result
+	}
+	for _, record := range records {
+		if strings.TrimSpace(record) == domain.VerificationValue() {
+			result.Owned = true
+			return result
+		}
+	}
+	result.OwnerError = fmt.Sprintf("TXT record %s does not contain %s", domain.VerificationRecord(), domain.VerificationValue())
+	return result
+}
+
+// sameAddresses reports whether a domain resolves to an address of target
+func (s *DomainService) sameAddresses(name string, target string) (bool, string) {
+	addrs, err := s.lookupHost(name)
+	if err != nil {
+		return false, fmt.Sprintf("failed to look up %s: %v", name, err)
+	}
+	targetAddrs, err := s.lookupHost(target)
+	if err != nil {
+		return false, fmt.Sprintf("failed to look up %s: %v", target, err)
+	}
+
+	for _, addr := range addrs {
+		for _, targetAddr := range targetAddrs {
+			if addr == targetAddr {
+				return true, ""
+			}
+		}
+	}
+	return false, fmt.Sprintf("%s is neither a CNAME of %s nor resolves to its addresses", name, target)
+}
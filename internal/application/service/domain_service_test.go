@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/logger"
+)
+
+// testDNS is a fake resolver for the lookup functions of DomainService
+type testDNS struct {
+	cnames map[string]string
+	txts   map[string][]string
+	hosts  map[string][]string
+}
+
+func (d testDNS) service() *DomainService {
+	s := NewDomainService(logger.NewLogger(io.Discard, "error"))
+	s.lookupCNAME = func(host string) (string, error) {
+		if cname, ok := d.cnames[host]; ok {
+			return cname, nil
+		}
+		// Without a CNAME, the resolver returns the name itself
+		if _, ok := d.hosts[host]; ok {
+			return host + ".", nil
+		}
+		return "", errors.New("no such host")
+	}
+	s.lookupTXT = func(name string) ([]string, error) {
+		if records, ok := d.txts[name]; ok {
+			return records, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	s.lookupHost = func(host string) ([]string, error) {
+		if addrs, ok := d.hosts[host]; ok {
+			return addrs, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	return s
+}
+
+func TestDomainServiceVerify(t *testing.T) {
+	domain := model.CustomDomain{Name: "app.example.com", Token: "abc123"}
+	apex := model.CustomDomain{Name: "example.com", Token: "abc123"}
+	owned := map[string][]string{
+		"_haxorport.app.example.com": {"other=1", " haxorport-verify=abc123 "},
+		"_haxorport.example.com":     {"haxorport-verify=abc123"},
+	}
+	server := map[string][]string{"haxorport.online": {"203.0.113.10"}}
+
+	tests := []struct {
+		name         string
+		domain       model.CustomDomain
+		dns          testDNS
+		wantRouted   bool
+		wantOwned    bool
+		wantRouteErr string
+		wantOwnerErr string
+	}{
+		{
+			name:       "CNAME to the base domain",
+			domain:     domain,
+			dns:        testDNS{cnames: map[string]string{"app.example.com": "haxorport.online."}, txts: owned},
+			wantRouted: true,
+			wantOwned:  true,
+		},
+		{
+			name:       "CNAME to a subdomain of the base domain",
+			domain:     domain,
+			dns:        testDNS{cnames: map[string]string{"app.example.com": "Edge.HaxorPort.Online."}, txts: owned},
+			wantRouted: true,
+			wantOwned:  true,
+		},
+		{
+			name:         "CNAME elsewhere",
+			domain:       domain,
+			dns:          testDNS{cnames: map[string]string{"app.example.com": "other.example.net."}, txts: owned},
+			wantOwned:    true,
+			wantRouteErr: "is a CNAME of other.example.net",
+		},
+		{
+			name:         "CNAME to a look-alike domain",
+			domain:       domain,
+			dns:          testDNS{cnames: map[string]string{"app.example.com": "evilhaxorport.online."}, txts: owned},
+			wantOwned:    true,
+			wantRouteErr: "is a CNAME of evilhaxorport.online",
+		},
+		{
+			name:       "flattened apex",
+			domain:     apex,
+			dns:        testDNS{hosts: map[string][]string{"example.com": {"198.51.100.1", "203.0.113.10"}, "haxorport.online": server["haxorport.online"]}, txts: owned},
+			wantRouted: true,
+			wantOwned:  true,
+		},
+		{
+			name:         "apex with other addresses",
+			domain:       apex,
+			dns:          testDNS{hosts: map[string][]string{"example.com": {"198.51.100.1"}, "haxorport.online": server["haxorport.online"]}, txts: owned},
+			wantOwned:    true,
+			wantRouteErr: "neither a CNAME of haxorport.online nor resolves to its addresses",
+		},
+		{
+			name:         "no DNS records",
+			domain:       domain,
+			dns:          testDNS{},
+			wantRouteErr: "failed to look up app.example.com",
+			wantOwnerErr: "failed to look up TXT record _haxorport.app.example.com",
+		},
+		{
+			name:         "wrong token",
+			domain:       domain,
+			dns:          testDNS{cnames: map[string]string{"app.example.com": "haxorport.online."}, txts: map[string][]string{"_haxorport.app.example.com": {"haxorport-verify=other"}}},
+			wantRouted:   true,
+			wantOwnerErr: "does not contain haxorport-verify=abc123",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.dns.service().Verify(tt.domain, "HaxorPort.online.")
+
+			if result.Target != "haxorport.online" {
+				t.Errorf("Target = %q, want haxorport.online", result.Target)
+			}
+			if result.Routed != tt.wantRouted || result.Owned != tt.wantOwned {
+				t.Errorf("Routed, Owned = %v, %v; want %v, %v (%s; %s)", result.Routed, result.Owned, tt.wantRouted, tt.wantOwned, result.RouteError, result.OwnerError)
+			}
+			if result.Verified() != (tt.wantRouted && tt.wantOwned) {
+				t.Errorf("Verified() = %v", result.Verified())
+			}
+			if !strings.Contains(result.RouteError, tt.wantRouteErr) || (tt.wantRouteErr == "") != (result.RouteError == "") {
+				t.Errorf("RouteError = %q, want %q", result.RouteError, tt.wantRouteErr)
+			}
+			if !strings.Contains(result.OwnerError, tt.wantOwnerErr) || (tt.wantOwnerErr == "") != (result.OwnerError == "") {
+				t.Errorf("OwnerError = %q, want %q", result.OwnerError, tt.wantOwnerErr)
+			}
+		})
+	}
+}
+
+func TestDomainServiceNewDomain(t *testing.T) {
+	s := testDNS{}.service()
+
+	domain, err := s.NewDomain(" App.Example.com. ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain.Name != "app.example.com" || len(domain.Token) != 32 || domain.Verified {
+		t.Errorf("NewDomain() = %+v", domain)
+	}
+	if other, _ := s.NewDomain("app.example.com"); other.Token == domain.Token {
+		t.Error("NewDomain returned the same token twice")
+	}
+
+	for _, name := range []string{"", "localhost", "-bad.example.com", "exa mple.com"} {
+		if _, err := s.NewDomain(name); err == nil {
+			t.Errorf("NewDomain(%q) succeeded, want error", name)
+		}
+	}
+}
@@ -23,18 +23,22 @@ func NewTunnelService(tunnelRepo port.TunnelRepository, logger port.Logger) *Tun
 
 
 func (s *TunnelService) CreateHTTPTunnel(localPort int, subdomain string, auth *model.TunnelAuth) (*model.Tunnel, error) {
-	s.logger.Info("Creating HTTP tunnel for local port %d with subdomain %s", localPort, subdomain)
-
-
-	tunnelConfig := model.TunnelConfig{
-		Type:      model.TunnelTypeHTTP,
+	return s.CreateHTTPTunnelWithConfig(model.TunnelConfig{
 		LocalPort: localPort,
 		Subdomain: subdomain,
 		Auth:      auth,
-	}
+	})
+}
+
+// CreateHTTPTunnelWithConfig creates an HTTP tunnel from a full tunnel configuration
+func (s *TunnelService) CreateHTTPTunnelWithConfig(config model.TunnelConfig) (*model.Tunnel, error) {
+	s.logger.Info("Creating HTTP tunnel for local port %d with subdomain %s", config.LocalPort, config.Subdomain)
+
+
+	config.Type = model.TunnelTypeHTTP
 
 	// Register tunnel
-	tunnel, err := s.tunnelRepo.Register(tunnelConfig)
+	tunnel, err := s.tunnelRepo.Register(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register HTTP tunnel: %v", err)
 	}
@@ -70,6 +74,52 @@ func (s *TunnelService) CreateTCPTunnel(config model.TunnelConfig) (*model.Tunne
 }
 
 
+// CreateUDPTunnel creates a UDP tunnel to a local UDP service
+func (s *TunnelService) CreateUDPTunnel(config model.TunnelConfig) (*model.Tunnel, error) {
+	if config.LocalAddr == "" {
+		config.LocalAddr = "127.0.0.1"
+	}
+
+	s.logger.Info("Creating UDP tunnel to %s:%d with remote port %d", config.LocalAddr, config.LocalPort, config.RemotePort)
+
+	config.Type = model.TunnelTypeUDP
+
+	// Register tunnel
+	tunnel, err := s.tunnelRepo.Register(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register UDP tunnel: %v", err)
+	}
+
+	s.logger.Info("UDP tunnel created successfully with remote port: %d", tunnel.RemotePort)
+
+	return tunnel, nil
+}
+
+
+// CreateTLSTunnel creates a TLS tunnel. Connections for the tunnel's
+// hostname are forwarded to the local TLS server without being decrypted,
+// unless the tunnel terminates TLS on the client with its own certificates.
+func (s *TunnelService) CreateTLSTunnel(config model.TunnelConfig) (*model.Tunnel, error) {
+	if config.LocalAddr == "" {
+		config.LocalAddr = "127.0.0.1"
+	}
+
+	s.logger.Info("Creating TLS tunnel for %s to %s:%d", config.Hostname, config.LocalAddr, config.LocalPort)
+
+	config.Type = model.TunnelTypeTLS
+
+	// Register tunnel
+	tunnel, err := s.tunnelRepo.Register(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register TLS tunnel: %v", err)
+	}
+
+	s.logger.Info("TLS tunnel created successfully for hostname: %s", config.Hostname)
+
+	return tunnel, nil
+}
+
+
 func (s *TunnelService) CloseTunnel(tunnelID string) error {
 	s.logger.Info("Closing tunnel with ID: %s", tunnelID)
 
@@ -21,6 +21,7 @@ type Container struct {
 	// Services
 	ConfigService *service.ConfigService
 	TunnelService *service.TunnelService
+	DomainService *service.DomainService
 
 	// Client
 	Client *transport.Client
@@ -78,6 +79,9 @@ func (c *Container) Initialize(configPath string) error {
 	// Inisialisasi tunnel service
 	c.TunnelService = service.NewTunnelService(c.TunnelRepository, c.Logger)
 
+	// Inisialisasi domain service
+	c.DomainService = service.NewDomainService(c.Logger)
+
 	// Daftarkan handler untuk pesan HTTP request
 	c.Client.RegisterHandler(model.MessageTypeHTTPRequest, c.Client.HandleHTTPRequestMessage)
 
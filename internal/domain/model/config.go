@@ -45,8 +45,14 @@ type Config struct {
 	LogFile string
 	// BaseDomain adalah domain dasar untuk subdomain tunnel
 	BaseDomain string
+	// BodyMemoryBudget adalah batas total byte body permintaan dan respons yang ditampung bersamaan (0 untuk default 512 MiB)
+	BodyMemoryBudget int64
+	// AdminAddress adalah alamat API admin lokal, misalnya 127.0.0.1:4040 (kosong untuk menonaktifkan)
+	AdminAddress string
 	// Tunnels adalah daftar tunnel yang akan dibuat saat startup
 	Tunnels []TunnelConfig
+	// Domains adalah daftar domain kustom yang ditambahkan dengan 'haxor domain add'
+	Domains []CustomDomain
 }
 
 // NewConfig membuat instance Config baru dengan nilai default
@@ -65,6 +71,7 @@ func NewConfig() *Config {
 		LogFile:           "",
 		BaseDomain:        "haxorport.online",
 		Tunnels:           []TunnelConfig{},
+		Domains:           []CustomDomain{},
 	}
 }
 
@@ -95,6 +102,34 @@ func (c *Config) GetTunnel(name string) *TunnelConfig {
 	return nil
 }
 
+// AddDomain menambahkan domain kustom ke konfigurasi
+func (c *Config) AddDomain(domain CustomDomain) {
+	c.Domains = append(c.Domains, domain)
+}
+
+// RemoveDomain menghapus domain kustom dari konfigurasi berdasarkan nama
+func (c *Config) RemoveDomain(name string) bool {
+	name = NormalizeHostname(name)
+	for i, domain := range c.Domains {
+		if domain.Name == name {
+			c.Domains = append(c.Domains[:i], c.Domains[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GetDomain mengembalikan domain kustom berdasarkan nama
+func (c *Config) GetDomain(name string) *CustomDomain {
+	name = NormalizeHostname(name)
+	for i := range c.Domains {
+		if c.Domains[i].Name == name {
+			return &c.Domains[i]
+		}
+	}
+	return nil
+}
+
 // GetConfigFilePath mengembalikan path ke file konfigurasi
 func (c *Config) GetConfigFilePath() string {
 	// Tentukan direktori konfigurasi berdasarkan user
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DomainVerificationPrefix is the label of the TXT record that proves the
+// ownership of a custom domain, e.g. _haxorport.app.example.com
+const DomainVerificationPrefix = "_haxorport"
+
+// CustomDomain is a custom domain added with 'haxor domain add'. Tunnels use
+// it through their hostname once its DNS records point to haxorport.
+type CustomDomain struct {
+	// Name is the domain, e.g. app.example.com
+	Name string `mapstructure:"name" yaml:"name"`
+	// Token is the value expected in the verification TXT record
+	Token string `mapstructure:"token" yaml:"token"`
+	// Verified is set once 'haxor domain verify' found both DNS records
+	Verified bool `mapstructure:"verified" yaml:"verified,omitempty"`
+}
+
+// VerificationRecord returns the name of the TXT record of the domain
+func (d CustomDomain) VerificationRecord() string {
+	return DomainVerificationPrefix + "." + d.Name
+}
+
+// VerificationValue returns the value expected in the TXT record of the domain
+func (d CustomDomain) VerificationValue() string {
+	return "haxorport-verify=" + d.Token
+}
+
+// DomainVerification is the result of checking the DNS records of a custom domain
+type DomainVerification struct {
+	// Target is the name the domain has to point to
+	Target string
+	// Routed is set when the domain is a CNAME of (or resolves like) the target
+	Routed bool
+	// RouteError explains why the domain is not routed to the target
+	RouteError string
+	// Owned is set when the TXT record contains the token of the domain
+	Owned bool
+	// OwnerError explains why the TXT record was not found
+	OwnerError string
+}
+
+// Verified reports whether both DNS records of the domain are in place
+func (v DomainVerification) Verified() bool {
+	return v.Routed && v.Owned
+}
+
+// NormalizeHostname returns a hostname in lower case without trailing dot
+func NormalizeHostname(hostname string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(hostname), "."))
+}
+
+// IsCustomDomain reports whether a hostname lies outside the base domain of
+// the server, so that it requires the custom domains feature
+func IsCustomDomain(hostname string, baseDomain string) bool {
+	hostname = strings.TrimPrefix(NormalizeHostname(hostname), "*.")
+	baseDomain = NormalizeHostname(baseDomain)
+	if hostname == "" {
+		return false
+	}
+	return baseDomain == "" || (hostname != baseDomain && !strings.HasSuffix(hostname, "."+baseDomain))
+}
+
+// ValidateDomainName checks that a name can be used as a custom domain
+func ValidateDomainName(name string) error {
+	if name == "" {
+		return fmt.Errorf("domain name cannot be empty")
+	}
+	if strings.Contains(name, "*") {
+		return fmt.Errorf("wildcard domains cannot be verified; add each hostname separately")
+	}
+	if len(name) > 253 || !strings.Contains(name, ".") {
+		return fmt.Errorf("invalid domain name: %s", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("invalid domain name: %s", name)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return fmt.Errorf("invalid domain name: %s", name)
+			}
+		}
+	}
+	return nil
+}
@@ -24,6 +24,8 @@ type HTTPRequest struct {
 	RemoteAddr string `json:"remote_addr"`
 	// Scheme adalah skema protokol (http atau https)
 	Scheme string `json:"scheme,omitempty"`
+	// Trailers adalah trailer permintaan yang dikirim setelah body (misalnya oleh klien gRPC)
+	Trailers http.Header `json:"trailers,omitempty"`
 }
 
 // HTTPResponse merepresentasikan respons HTTP yang dikirim dari client ke server
@@ -36,6 +38,8 @@ type HTTPResponse struct {
 	Headers http.Header `json:"headers"`
 	// Body adalah body respons
 	Body []byte `json:"body,omitempty"`
+	// Trailers adalah trailer respons yang dikirim setelah body (misalnya grpc-status)
+	Trailers http.Header `json:"trailers,omitempty"`
 	// Error adalah error yang terjadi (jika ada)
 	Error string `json:"error,omitempty"`
 }
@@ -24,6 +24,12 @@ const (
 	MessageTypePong MessageType = "pong"
 	// MessageTypeError indicates an error message
 	MessageTypeError MessageType = "error"
+	// MessageTypeDatagram carries a datagram of a UDP tunnel session
+	MessageTypeDatagram MessageType = "datagram"
+	// MessageTypeDatagramClose ends a UDP tunnel session
+	MessageTypeDatagramClose MessageType = "datagram_close"
+	// MessageTypeClose ends a connection of a TCP or TLS tunnel
+	MessageTypeClose MessageType = "close"
 )
 
 // Message represents the base structure for all client-server messages
@@ -74,15 +80,18 @@ type AuthPayload struct {
 
 // RegisterPayload is for tunnel registration messages
 type RegisterPayload struct {
-	// TunnelType specifies the tunnel type (http, tcp)
+	// TunnelType specifies the tunnel type (http, tcp, udp, tls)
 	TunnelType string `json:"tunnel_type"`
 	// Subdomain is the requested subdomain (optional)
 	Subdomain string `json:"subdomain,omitempty"`
+	// Hostname is the full hostname routed to the tunnel, such as the SNI
+	// hostname of TLS tunnels (optional)
+	Hostname string `json:"hostname,omitempty"`
 	// LocalAddr is the specific local address for forwarding (optional)
 	LocalAddr string `json:"local_addr,omitempty"`
 	// LocalPort is the local port to be tunneled
 	LocalPort int `json:"local_port"`
-	// RemotePort is the requested remote port (for TCP, optional)
+	// RemotePort is the requested remote port (for TCP and UDP, optional)
 	RemotePort int `json:"remote_port,omitempty"`
 	// Auth contains tunnel authentication information (optional)
 	Auth *TunnelAuth `json:"auth,omitempty"`
@@ -102,6 +111,37 @@ type DataPayload struct {
 	ConnectionID string `json:"connection_id"`
 	// Data is the actual data being sent
 	Data []byte `json:"data"`
+	// RemoteAddr is the address of the visitor (set by the server on the
+	// first data message of a connection)
+	RemoteAddr string `json:"remote_addr,omitempty"`
+}
+
+// ClosePayload is for messages that end a connection of a TCP or TLS tunnel
+type ClosePayload struct {
+	// TunnelID is the ID of the tunnel associated with the connection
+	TunnelID string `json:"tunnel_id"`
+	// ConnectionID is the ID of the connection that ended
+	ConnectionID string `json:"connection_id"`
+}
+
+// DatagramPayload is for datagram messages of UDP tunnels
+type DatagramPayload struct {
+	// TunnelID is the ID of the tunnel associated with the datagram
+	TunnelID string `json:"tunnel_id"`
+	// SessionID identifies the remote peer; datagrams of the same peer share a session
+	SessionID string `json:"session_id"`
+	// RemoteAddr is the address of the remote peer (set by the server)
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	// Data is the datagram
+	Data []byte `json:"data"`
+}
+
+// DatagramClosePayload is for messages that end a UDP tunnel session
+type DatagramClosePayload struct {
+	// TunnelID is the ID of the tunnel associated with the session
+	TunnelID string `json:"tunnel_id"`
+	// SessionID is the ID of the session that ended
+	SessionID string `json:"session_id"`
 }
 
 // ErrorPayload is for error messages
@@ -120,7 +160,7 @@ type RegisterResponsePayload struct {
 	TunnelID string `json:"tunnel_id"`
 	// URL is the public URL for HTTP tunnels
 	URL string `json:"url,omitempty"`
-	// RemotePort is the remote port for TCP tunnels
+	// RemotePort is the remote port for TCP and UDP tunnels
 	RemotePort int `json:"remote_port,omitempty"`
 	// Error contains the error message if registration failed
 	Error string `json:"error,omitempty"`
@@ -8,6 +8,10 @@ const (
 	TunnelTypeHTTP TunnelType = "http"
 
 	TunnelTypeTCP TunnelType = "tcp"
+
+	TunnelTypeUDP TunnelType = "udp"
+
+	TunnelTypeTLS TunnelType = "tls"
 )
 
 
@@ -18,6 +22,18 @@ const (
 	AuthTypeBasic AuthType = "basic"
 
 	AuthTypeHeader AuthType = "header"
+
+	AuthTypeOIDC AuthType = "oidc"
+
+	AuthTypeJWT AuthType = "jwt"
+)
+
+
+const (
+
+	HostHeaderPreserve = "preserve"
+
+	HostHeaderRewrite = "rewrite"
 )
 
 
@@ -29,9 +45,35 @@ type TunnelAuth struct {
 
 	Password string
 
+	PasswordHash string `mapstructure:"password_hash" yaml:"password_hash,omitempty"`
+
 	HeaderName string
 
 	HeaderValue string
+
+	Issuer string `mapstructure:"issuer" yaml:"issuer,omitempty"`
+
+	ClientID string `mapstructure:"client_id" yaml:"client_id,omitempty"`
+
+	ClientSecret string `mapstructure:"client_secret" yaml:"client_secret,omitempty"`
+
+	AllowedEmails []string `mapstructure:"allowed_emails" yaml:"allowed_emails,omitempty"`
+
+	AllowedDomains []string `mapstructure:"allowed_domains" yaml:"allowed_domains,omitempty"`
+
+	CookieSecret string `mapstructure:"cookie_secret" yaml:"cookie_secret,omitempty"`
+
+	JWTSecret string `mapstructure:"jwt_secret" yaml:"jwt_secret,omitempty"`
+
+	JWTPublicKey string `mapstructure:"jwt_public_key" yaml:"jwt_public_key,omitempty"`
+
+	JWKS string `mapstructure:"jwks" yaml:"jwks,omitempty"`
+
+	Audience string `mapstructure:"audience" yaml:"audience,omitempty"`
+
+	RequiredClaims map[string]string `mapstructure:"required_claims" yaml:"required_claims,omitempty"`
+
+	ClaimHeaders map[string]string `mapstructure:"claim_headers" yaml:"claim_headers,omitempty"`
 }
 
 
@@ -50,6 +92,56 @@ type TunnelConfig struct {
 	RemotePort int
 
 	Auth *TunnelAuth
+
+	HostHeader string `mapstructure:"host_header" yaml:"host_header,omitempty"`
+
+	Routes []TunnelRoute `mapstructure:"routes" yaml:"routes,omitempty"`
+
+	Upstreams []string `mapstructure:"upstreams" yaml:"upstreams,omitempty"`
+
+	LoadBalance LoadBalanceStrategy `mapstructure:"load_balance" yaml:"load_balance,omitempty"`
+
+	HashHeader string `mapstructure:"hash_header" yaml:"hash_header,omitempty"`
+
+	HealthCheck *HealthCheckConfig `mapstructure:"health_check" yaml:"health_check,omitempty"`
+
+	Scheme string `mapstructure:"scheme" yaml:"scheme,omitempty"`
+
+	UpstreamTLS *UpstreamTLSConfig `mapstructure:"upstream_tls" yaml:"upstream_tls,omitempty"`
+
+	Upstream string `mapstructure:"upstream" yaml:"upstream,omitempty"`
+
+	RewriteURLs bool `mapstructure:"rewrite_urls" yaml:"rewrite_urls,omitempty"`
+
+	RewriteHeaders bool `mapstructure:"rewrite_headers" yaml:"rewrite_headers,omitempty"`
+
+	IPPolicy *IPPolicyConfig `mapstructure:"ip_policy" yaml:"ip_policy,omitempty"`
+
+	RateLimit *RateLimitConfig `mapstructure:"rate_limit" yaml:"rate_limit,omitempty"`
+
+	Verify *WebhookVerifyConfig `mapstructure:"verify" yaml:"verify,omitempty"`
+
+	ErrorPages map[string]string `mapstructure:"error_pages" yaml:"error_pages,omitempty"`
+
+	Timeouts *UpstreamTimeoutConfig `mapstructure:"timeouts" yaml:"timeouts,omitempty"`
+
+	MockFile string `mapstructure:"mock_file" yaml:"mock_file,omitempty"`
+
+	Faults *FaultConfig `mapstructure:"faults" yaml:"faults,omitempty"`
+
+	MaxRequestBody int64 `mapstructure:"max_request_body" yaml:"max_request_body,omitempty"`
+
+	MaxResponseBody int64 `mapstructure:"max_response_body" yaml:"max_response_body,omitempty"`
+
+	CORS *CORSConfig `mapstructure:"cors" yaml:"cors,omitempty"`
+
+	Protocol UpstreamProtocol `mapstructure:"protocol" yaml:"protocol,omitempty"`
+
+	UDP *UDPConfig `mapstructure:"udp" yaml:"udp,omitempty"`
+
+	Hostname string `mapstructure:"hostname" yaml:"hostname,omitempty"`
+
+	TLSTermination *TLSTerminationConfig `mapstructure:"tls_termination" yaml:"tls_termination,omitempty"`
 }
 
 
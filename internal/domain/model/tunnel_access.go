@@ -0,0 +1,32 @@
+package model
+
+// IPPolicyConfig restricts which visitor addresses can reach a tunnel.
+// Deny rules win over allow rules; when any allow rule is set, visitors
+// must match at least one of them.
+type IPPolicyConfig struct {
+	// Allow lists the CIDR ranges or single IPs allowed to connect
+	Allow []string `mapstructure:"allow" yaml:"allow,omitempty"`
+	// Deny lists the CIDR ranges or single IPs that are always rejected
+	Deny []string `mapstructure:"deny" yaml:"deny,omitempty"`
+	// GeoIPDatabase is a local MaxMind MMDB file used for country rules
+	GeoIPDatabase string `mapstructure:"geoip_database" yaml:"geoip_database,omitempty"`
+	// AllowCountries lists the ISO country codes allowed to connect
+	AllowCountries []string `mapstructure:"allow_countries" yaml:"allow_countries,omitempty"`
+	// DenyCountries lists the ISO country codes that are always rejected
+	DenyCountries []string `mapstructure:"deny_countries" yaml:"deny_countries,omitempty"`
+}
+
+// RateLimitConfig limits the requests an HTTP tunnel forwards to the local
+// service. Rates are in requests per second; zero disables a limit.
+type RateLimitConfig struct {
+	// Rate is the request rate allowed for the whole tunnel
+	Rate float64 `mapstructure:"rate" yaml:"rate,omitempty"`
+	// Burst is the number of requests allowed at once above Rate (default Rate, at least 1)
+	Burst int `mapstructure:"burst" yaml:"burst,omitempty"`
+	// PerIPRate is the request rate allowed for each visitor address
+	PerIPRate float64 `mapstructure:"per_ip_rate" yaml:"per_ip_rate,omitempty"`
+	// PerIPBurst is the burst allowed for each visitor address (default PerIPRate, at least 1)
+	PerIPBurst int `mapstructure:"per_ip_burst" yaml:"per_ip_burst,omitempty"`
+	// MaxInFlight caps the requests being handled by the local service at once
+	MaxInFlight int `mapstructure:"max_in_flight" yaml:"max_in_flight,omitempty"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// CORSConfig configures the CORS headers the client adds to the responses of
+// an HTTP tunnel. Preflight requests are answered by the client itself.
+type CORSConfig struct {
+	// AllowOrigins are the origins allowed to call the tunnel: "*", exact
+	// origins such as "https://app.example.com", or wildcards such as
+	// "https://*.example.com"
+	AllowOrigins []string `mapstructure:"allow_origins" yaml:"allow_origins,omitempty"`
+	// AllowMethods are the methods allowed in cross-origin requests
+	// (default GET, HEAD, POST, PUT, PATCH and DELETE)
+	AllowMethods []string `mapstructure:"allow_methods" yaml:"allow_methods,omitempty"`
+	// AllowHeaders are the request headers allowed in cross-origin requests
+	// (default: the headers the browser asks for)
+	AllowHeaders []string `mapstructure:"allow_headers" yaml:"allow_headers,omitempty"`
+	// ExposeHeaders are the response headers scripts may read
+	ExposeHeaders []string `mapstructure:"expose_headers" yaml:"expose_headers,omitempty"`
+	// AllowCredentials allows cookies and authorization headers in
+	// cross-origin requests; it cannot be combined with the "*" origin
+	AllowCredentials bool `mapstructure:"allow_credentials" yaml:"allow_credentials,omitempty"`
+	// MaxAge is how long browsers may cache a preflight response (default 10m)
+	MaxAge time.Duration `mapstructure:"max_age" yaml:"max_age,omitempty"`
+}
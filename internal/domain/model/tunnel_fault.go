@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// FaultConfig configures fault injection into a tunnel for resilience testing
+type FaultConfig struct {
+	// Paths are path globs of the HTTP requests to inject faults into (empty for all paths)
+	Paths []string `mapstructure:"paths" yaml:"paths,omitempty"`
+	// Probability is the chance that a request or TCP connection is affected (default 1)
+	Probability float64 `mapstructure:"probability" yaml:"probability,omitempty"`
+	// Latency is added before an affected request is forwarded or a TCP chunk is sent
+	Latency time.Duration `mapstructure:"latency" yaml:"latency,omitempty"`
+	// Jitter adds a random extra latency of up to this duration
+	Jitter time.Duration `mapstructure:"jitter" yaml:"jitter,omitempty"`
+	// ErrorRate is the chance that an affected HTTP request is answered with one of ErrorStatuses
+	ErrorRate float64 `mapstructure:"error_rate" yaml:"error_rate,omitempty"`
+	// ErrorStatuses are the statuses of injected errors (default 500, 502, 503 and 504)
+	ErrorStatuses []int `mapstructure:"error_statuses" yaml:"error_statuses,omitempty"`
+	// DropRate is the chance that an affected HTTP request gets no response, or
+	// that an affected TCP connection is closed before each chunk
+	DropRate float64 `mapstructure:"drop_rate" yaml:"drop_rate,omitempty"`
+	// Bandwidth throttles affected responses and TCP connections to this many bytes per second
+	Bandwidth int `mapstructure:"bandwidth" yaml:"bandwidth,omitempty"`
+	// Paused starts the tunnel with fault injection turned off, so that it can
+	// be turned on later from the admin API
+	Paused bool `mapstructure:"paused" yaml:"paused,omitempty"`
+}
+
+// FaultStatus is the runtime state of fault injection of a tunnel
+type FaultStatus struct {
+	// Enabled tells whether faults are currently injected
+	Enabled bool
+	// Config is the fault configuration of the tunnel
+	Config FaultConfig
+	// Delayed is the number of requests and chunks delayed by latency or throttling
+	Delayed int64
+	// Errors is the number of injected error responses
+	Errors int64
+	// Dropped is the number of dropped requests and connections
+	Dropped int64
+}
@@ -0,0 +1,39 @@
+package model
+
+// MockRule answers matching requests directly from the client with a static
+// response instead of forwarding them to the local service
+type MockRule struct {
+	// Name identifies the rule in logs and the X-Haxor-Mock response header
+	Name string `mapstructure:"name" yaml:"name,omitempty"`
+	// Method is the HTTP method to match (empty matches any method)
+	Method string `mapstructure:"method" yaml:"method,omitempty"`
+	// Path is a glob matched against the request path: "*" matches within a
+	// path segment and "**" across segments (empty matches any path)
+	Path string `mapstructure:"path" yaml:"path,omitempty"`
+	// Headers are request headers that must be present; values are globs
+	// where "*" matches anything
+	Headers map[string]string `mapstructure:"headers" yaml:"headers,omitempty"`
+	// Status is the response status code (default 200)
+	Status int `mapstructure:"status" yaml:"status,omitempty"`
+	// ResponseHeaders are the response headers
+	ResponseHeaders map[string]string `mapstructure:"response_headers" yaml:"response_headers,omitempty"`
+	// Body is the response body
+	Body string `mapstructure:"body" yaml:"body,omitempty"`
+	// File is a file sent as the response body instead of Body. It is read
+	// for every request, so edits show up immediately.
+	File string `mapstructure:"file" yaml:"file,omitempty"`
+}
+
+// MockFile is the format of a mock rules file
+type MockFile struct {
+	// Mocks are the rules of the file, checked in order
+	Mocks []MockRule `mapstructure:"mocks" yaml:"mocks"`
+}
+
+// MockStatus is the mock file of a tunnel with the rules loaded from it
+type MockStatus struct {
+	// File is the path of the mock file
+	File string
+	// Rules are the rules in use, checked in order
+	Rules []MockRule
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TunnelRoute maps requests on an HTTP tunnel to a local service
+type TunnelRoute struct {
+	// Path is a path prefix such as "/api/*" (longest prefix wins)
+	Path string `mapstructure:"path" yaml:"path,omitempty"`
+	// Regex is a regular expression matched against the request path (optional, replaces Path)
+	Regex string `mapstructure:"regex" yaml:"regex,omitempty"`
+	// Target is the local address, e.g. "8080" or "localhost:8080"
+	Target string `mapstructure:"target" yaml:"target"`
+	// StripPrefix removes the matched prefix before the request is forwarded
+	StripPrefix bool `mapstructure:"strip_prefix" yaml:"strip_prefix,omitempty"`
+}
+
+// ParseTunnelRoute parses a route given on the command line.
+// The format is PATTERN=TARGET[,strip], where a PATTERN starting with "~"
+// is a regular expression, for example:
+//
+//	/api/*=8080,strip
+//	~^/v[0-9]+/=localhost:9000
+func ParseTunnelRoute(spec string) (TunnelRoute, error) {
+	var route TunnelRoute
+
+	idx := strings.LastIndex(spec, "=")
+	if idx <= 0 || idx == len(spec)-1 {
+		return route, fmt.Errorf("invalid route %q, expected PATTERN=TARGET", spec)
+	}
+	pattern, target := spec[:idx], spec[idx+1:]
+
+	if strings.HasSuffix(target, ",strip") {
+		route.StripPrefix = true
+		target = strings.TrimSuffix(target, ",strip")
+	}
+	route.Target = target
+
+	if strings.HasPrefix(pattern, "~") {
+		route.Regex = strings.TrimPrefix(pattern, "~")
+	} else {
+		if !strings.HasPrefix(pattern, "/") {
+			return route, fmt.Errorf("invalid route %q, path must start with /", spec)
+		}
+		route.Path = pattern
+	}
+
+	return route, nil
+}
+
+// String returns the route in the command line format
+func (r TunnelRoute) String() string {
+	pattern := r.Path
+	if r.Regex != "" {
+		pattern = "~" + r.Regex
+	}
+	s := pattern + "=" + r.Target
+	if r.StripPrefix {
+		s += ",strip"
+	}
+	return s
+}
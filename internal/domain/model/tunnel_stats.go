@@ -0,0 +1,17 @@
+package model
+
+// TunnelStats holds the request counters of an HTTP tunnel
+type TunnelStats struct {
+	// Requests is the number of requests received
+	Requests int64
+	// RateLimited is the number of requests rejected with 429 Too Many Requests
+	RateLimited int64
+	// InFlight is the number of requests currently being handled
+	InFlight int64
+	// PeakInFlight is the highest number of requests handled at once
+	PeakInFlight int64
+	// RateLimit is the rate limit configuration of the tunnel, if any
+	RateLimit *RateLimitConfig
+	// Webhook holds the webhook signature verification results, if the tunnel verifies them
+	Webhook *WebhookStatus
+}
@@ -0,0 +1,19 @@
+package model
+
+// TLSTerminationConfig makes the client terminate the TLS connections of a
+// TLS tunnel with its own certificates and proxy the decrypted requests to
+// the local HTTP service, so that no plaintext passes through the relay.
+type TLSTerminationConfig struct {
+	// Certificates are the certificates served to visitors; the one whose
+	// names match the SNI hostname is used, the first one otherwise
+	Certificates []TLSCertificateFiles `mapstructure:"certificates" yaml:"certificates,omitempty"`
+}
+
+// TLSCertificateFiles is a PEM certificate chain with its private key. The
+// files are reloaded when they change.
+type TLSCertificateFiles struct {
+	// CertFile is the PEM certificate chain
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file"`
+	// KeyFile is the PEM private key
+	KeyFile string `mapstructure:"key_file" yaml:"key_file"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// UDPConfig configures the peer sessions of a UDP tunnel. Every peer that
+// sends datagrams to the tunnel gets its own session on the client.
+type UDPConfig struct {
+	// IdleTimeout is how long a session is kept without datagrams in either
+	// direction (default 60s)
+	IdleTimeout time.Duration `mapstructure:"idle_timeout" yaml:"idle_timeout,omitempty"`
+	// MaxSessions is the maximum number of concurrent sessions (default 1024)
+	MaxSessions int `mapstructure:"max_sessions" yaml:"max_sessions,omitempty"`
+}
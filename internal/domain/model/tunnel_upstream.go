@@ -0,0 +1,85 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// LoadBalanceStrategy defines how requests are spread across upstreams
+type LoadBalanceStrategy string
+
+const (
+	// LoadBalanceRoundRobin sends requests to each upstream in turn
+	LoadBalanceRoundRobin LoadBalanceStrategy = "round_robin"
+	// LoadBalanceLeastConn sends requests to the upstream with the fewest active requests
+	LoadBalanceLeastConn LoadBalanceStrategy = "least_conn"
+	// LoadBalanceHash sends requests with the same header value to the same upstream
+	LoadBalanceHash LoadBalanceStrategy = "hash"
+)
+
+// UpstreamProtocol defines the HTTP version used to talk to the local service
+type UpstreamProtocol string
+
+const (
+	// UpstreamProtocolAuto uses HTTP/1.1, or HTTP/2 when an HTTPS upstream offers it
+	UpstreamProtocolAuto UpstreamProtocol = "auto"
+	// UpstreamProtocolHTTP1 always uses HTTP/1.1
+	UpstreamProtocolHTTP1 UpstreamProtocol = "http1"
+	// UpstreamProtocolH2C uses HTTP/2 without TLS (prior knowledge), as plaintext gRPC servers expect
+	UpstreamProtocolH2C UpstreamProtocol = "h2c"
+)
+
+// HealthCheckConfig configures active health checks of upstreams
+type HealthCheckConfig struct {
+	// Path is the HTTP path to probe (empty for a plain TCP connect check)
+	Path string `mapstructure:"path" yaml:"path,omitempty"`
+	// Interval is the time between two checks (default 10s)
+	Interval time.Duration `mapstructure:"interval" yaml:"interval,omitempty"`
+	// Timeout is the maximum duration of a single check (default 2s)
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout,omitempty"`
+}
+
+// UpstreamTLSConfig configures TLS connections to HTTPS upstreams
+type UpstreamTLSConfig struct {
+	// CAFile is a PEM file with CA certificates used to verify the upstream
+	CAFile string `mapstructure:"ca_file" yaml:"ca_file,omitempty"`
+	// InsecureSkipVerify disables verification of the upstream certificate
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify" yaml:"insecure_skip_verify,omitempty"`
+	// CertFile is a PEM client certificate presented to the upstream (optional)
+	CertFile string `mapstructure:"cert_file" yaml:"cert_file,omitempty"`
+	// KeyFile is the PEM key of the client certificate
+	KeyFile string `mapstructure:"key_file" yaml:"key_file,omitempty"`
+	// ServerName overrides the name used for SNI and verification (optional)
+	ServerName string `mapstructure:"server_name" yaml:"server_name,omitempty"`
+}
+
+// UpstreamTimeoutConfig configures the timeouts and retries of requests to
+// the local service. Zero values use the defaults.
+type UpstreamTimeoutConfig struct {
+	// Dial is the maximum time to connect to the local service (default 10s)
+	Dial time.Duration `mapstructure:"dial" yaml:"dial,omitempty"`
+	// TLSHandshake is the maximum time of the TLS handshake with HTTPS upstreams (default 10s)
+	TLSHandshake time.Duration `mapstructure:"tls_handshake" yaml:"tls_handshake,omitempty"`
+	// ResponseHeader is the maximum time to wait for the response headers (default 60s)
+	ResponseHeader time.Duration `mapstructure:"response_header" yaml:"response_header,omitempty"`
+	// Idle is how long an idle keep-alive connection is kept open (default 90s)
+	Idle time.Duration `mapstructure:"idle" yaml:"idle,omitempty"`
+	// Retries is how often idempotent requests are retried when the local
+	// service refuses the connection, for example while it restarts (default 0)
+	Retries int `mapstructure:"retries" yaml:"retries,omitempty"`
+	// RetryBackoff is the wait before the first retry, doubled for each further retry (default 250ms)
+	RetryBackoff time.Duration `mapstructure:"retry_backoff" yaml:"retry_backoff,omitempty"`
+}
+
+// UnixSocketPath returns the socket path of a Unix socket upstream address
+// such as "unix:///run/app.sock" or "unix:/run/app.sock", or an empty string
+// when addr is not a Unix socket address
+func UnixSocketPath(addr string) string {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return strings.TrimPrefix(addr, "unix://")
+	case strings.HasPrefix(addr, "unix:"):
+		return strings.TrimPrefix(addr, "unix:")
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// WebhookProvider identifies how a webhook provider signs its requests
+type WebhookProvider string
+
+const (
+	// WebhookProviderGitHub verifies X-Hub-Signature-256
+	WebhookProviderGitHub WebhookProvider = "github"
+	// WebhookProviderStripe verifies Stripe-Signature, including its timestamp
+	WebhookProviderStripe WebhookProvider = "stripe"
+	// WebhookProviderSlack verifies X-Slack-Signature and X-Slack-Request-Timestamp
+	WebhookProviderSlack WebhookProvider = "slack"
+	// WebhookProviderTwilio verifies X-Twilio-Signature
+	WebhookProviderTwilio WebhookProvider = "twilio"
+	// WebhookProviderShopify verifies X-Shopify-Hmac-Sha256
+	WebhookProviderShopify WebhookProvider = "shopify"
+	// WebhookProviderGeneric verifies a hex HMAC-SHA256 of the body in a custom header
+	WebhookProviderGeneric WebhookProvider = "generic"
+)
+
+// WebhookVerifyConfig configures webhook signature verification of an HTTP tunnel
+type WebhookVerifyConfig struct {
+	// Provider selects the signature scheme
+	Provider WebhookProvider `mapstructure:"provider" yaml:"provider"`
+	// Secret is the signing secret (the auth token for Twilio)
+	Secret string `mapstructure:"secret" yaml:"secret"`
+	// Tolerance is the maximum age of signed timestamps (default 5m)
+	Tolerance time.Duration `mapstructure:"tolerance" yaml:"tolerance,omitempty"`
+	// Header is the signature header of the generic provider
+	Header string `mapstructure:"header" yaml:"header,omitempty"`
+}
+
+// WebhookStatus holds the signature verification results of a tunnel
+type WebhookStatus struct {
+	// Provider is the signature scheme that is verified
+	Provider WebhookProvider
+	// Verified is the number of requests with a valid signature
+	Verified int64
+	// Rejected is the number of requests with a missing or invalid signature
+	Rejected int64
+	// Last is the result of the latest verification, if any
+	Last *WebhookResult
+}
+
+// WebhookResult is the result of verifying the signature of a request
+type WebhookResult struct {
+	// Method and URL identify the request
+	Method string
+	URL    string
+	// Verified tells whether the signature was valid
+	Verified bool
+	// Error is the reason a signature was rejected
+	Error string
+	// Time is when the request was verified
+	Time time.Time
+}
@@ -0,0 +1,387 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/application/service"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/transport"
+)
+
+// Server is the local admin API of the client. It lists the tunnels of the
+// client with their request counters and webhook verification results, and
+// turns fault injection on and off and edits mock rules.
+//
+//	GET /api/tunnels              list tunnels
+//	GET /api/tunnels/{id}         show a tunnel
+//	GET /api/tunnels/{id}/faults  show the fault injection state of a tunnel
+//	PUT /api/tunnels/{id}/faults  turn fault injection on or off: {"enabled": true}
+//	GET /api/tunnels/{id}/mocks   show the mock rules of an HTTP tunnel
+//	PUT /api/tunnels/{id}/mocks   replace the mock rules with a YAML or JSON mock file
+//
+// Requests are only served for loopback host names and the host of the
+// address the API listens on, so that web pages cannot reach it through
+// DNS rebinding.
+type Server struct {
+	client  *transport.Client
+	tunnels *service.TunnelService
+	logger  port.Logger
+	server  *http.Server
+	hosts   map[string]bool
+}
+
+// tunnelView is the JSON representation of a tunnel
+type tunnelView struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name,omitempty"`
+	Type       string      `json:"type"`
+	URL        string      `json:"url,omitempty"`
+	RemotePort int         `json:"remote_port,omitempty"`
+	LocalPort  int         `json:"local_port,omitempty"`
+	Upstream   string      `json:"upstream,omitempty"`
+	Stats      *statsView  `json:"stats,omitempty"`
+	Faults     *faultsView `json:"faults,omitempty"`
+}
+
+// statsView is the JSON representation of the request counters of a tunnel
+type statsView struct {
+	Requests     int64        `json:"requests"`
+	RateLimited  int64        `json:"rate_limited"`
+	InFlight     int64        `json:"in_flight"`
+	PeakInFlight int64        `json:"peak_in_flight"`
+	Webhook      *webhookView `json:"webhook,omitempty"`
+}
+
+// webhookView is the JSON representation of the webhook verification results of a tunnel
+type webhookView struct {
+	Provider string             `json:"provider"`
+	Verified int64              `json:"verified"`
+	Rejected int64              `json:"rejected"`
+	Last     *webhookResultView `json:"last,omitempty"`
+}
+
+// webhookResultView is the JSON representation of the latest webhook verification
+type webhookResultView struct {
+	Method   string    `json:"method"`
+	URL      string    `json:"url"`
+	Verified bool      `json:"verified"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// faultsView is the JSON representation of the fault injection state of a tunnel
+type faultsView struct {
+	Enabled       bool     `json:"enabled"`
+	Paths         []string `json:"paths,omitempty"`
+	Probability   float64  `json:"probability"`
+	Latency       string   `json:"latency,omitempty"`
+	Jitter        string   `json:"jitter,omitempty"`
+	ErrorRate     float64  `json:"error_rate,omitempty"`
+	ErrorStatuses []int    `json:"error_statuses,omitempty"`
+	DropRate      float64  `json:"drop_rate,omitempty"`
+	Bandwidth     int      `json:"bandwidth,omitempty"`
+	Delayed       int64    `json:"delayed"`
+	Errors        int64    `json:"errors"`
+	Dropped       int64    `json:"dropped"`
+}
+
+// mocksView is the JSON representation of the mock rules of a tunnel
+type mocksView struct {
+	File  string         `json:"file"`
+	Rules []mockRuleView `json:"rules"`
+}
+
+// mockRuleView is the JSON representation of a mock rule
+type mockRuleView struct {
+	Name            string            `json:"name,omitempty"`
+	Method          string            `json:"method,omitempty"`
+	Path            string            `json:"path,omitempty"`
+	Headers         map[string]string `json:"headers,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Body            string            `json:"body,omitempty"`
+	File            string            `json:"file,omitempty"`
+}
+
+// maxMockFileSize limits the mock files accepted by the admin API
+const maxMockFileSize = 1 << 20
+
+// NewServer creates the admin API
+func NewServer(client *transport.Client, tunnels *service.TunnelService, logger port.Logger) *Server {
+	return &Server{
+		client:  client,
+		tunnels: tunnels,
+		logger:  logger,
+		hosts:   map[string]bool{"localhost": true, "127.0.0.1": true, "::1": true},
+	}
+}
+
+// Start serves the admin API on addr and returns the address it listens on
+func (s *Server) Start(addr string) (string, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to start admin API: %v", err)
+	}
+	if ip := listener.Addr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+		s.logger.Warn("Admin API listens on %s, which is reachable from other machines and has no authentication", listener.Addr())
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		s.hosts[requestHost(host)] = true
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Admin API stopped: %v", err)
+		}
+	}()
+
+	return listener.Addr().String(), nil
+}
+
+// Close stops the admin API
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// Handler returns the HTTP handler of the admin API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tunnels", s.handleTunnels)
+	mux.HandleFunc("/api/tunnels/", s.handleTunnel)
+	return s.checkHost(mux)
+}
+
+// checkHost rejects requests whose Host header is not an allowed host name
+func (s *Server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.hosts[requestHost(r.Host)] {
+			writeError(w, http.StatusForbidden, "invalid Host header")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleTunnels lists the tunnels of the client
+func (s *Server) handleTunnels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	tunnels := s.tunnels.GetAllTunnels()
+	views := make([]tunnelView, 0, len(tunnels))
+	for _, tunnel := range tunnels {
+		views = append(views, s.tunnelView(tunnel))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// handleTunnel serves /api/tunnels/{id} and /api/tunnels/{id}/faults
+func (s *Server) handleTunnel(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/tunnels/"), "/"), "/")
+	tunnel, err := s.tunnels.GetTunnelByID(parts[0])
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.tunnelView(tunnel))
+
+	case len(parts) == 2 && parts[1] == "faults" && r.Method == http.MethodGet:
+		status, ok := s.client.FaultStatus(tunnel.ID)
+		if !ok {
+			writeError(w, http.StatusNotFound, "tunnel has no fault injection configured")
+			return
+		}
+		writeJSON(w, http.StatusOK, newFaultsView(status))
+
+	case len(parts) == 2 && parts[1] == "faults" && (r.Method == http.MethodPut || r.Method == http.MethodPost):
+		// A JSON content type makes browsers send a CORS preflight, so that
+		// web pages cannot change faults through the visitor's browser
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, "expected Content-Type: application/json")
+			return
+		}
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+			writeError(w, http.StatusBadRequest, `expected a JSON body such as {"enabled": true}`)
+			return
+		}
+		status, ok := s.client.SetFaultsEnabled(tunnel.ID, *body.Enabled)
+		if !ok {
+			writeError(w, http.StatusNotFound, "tunnel has no fault injection configured")
+			return
+		}
+		writeJSON(w, http.StatusOK, newFaultsView(status))
+
+	case len(parts) == 2 && parts[1] == "mocks" && r.Method == http.MethodGet:
+		status, ok := s.client.MockRules(tunnel.ID)
+		if !ok {
+			writeError(w, http.StatusNotFound, "tunnel has no mock file configured")
+			return
+		}
+		writeJSON(w, http.StatusOK, newMocksView(status))
+
+	case len(parts) == 2 && parts[1] == "mocks" && r.Method == http.MethodPut:
+		// YAML and JSON content types make browsers send a CORS preflight
+		if !isMockFileType(r.Header.Get("Content-Type")) {
+			writeError(w, http.StatusUnsupportedMediaType, "expected Content-Type: application/yaml or application/json")
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMockFileSize))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, "mock file too large")
+			return
+		}
+		status, ok, err := s.client.SetMockRules(tunnel.ID, data)
+		switch {
+		case !ok:
+			writeError(w, http.StatusNotFound, "tunnel has no mock file configured")
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, newMocksView(status))
+		}
+
+	case len(parts) <= 2:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// tunnelView describes a tunnel with its request counters and fault state
+func (s *Server) tunnelView(tunnel *model.Tunnel) tunnelView {
+	view := tunnelView{
+		ID:         tunnel.ID,
+		Name:       tunnel.Config.Name,
+		Type:       string(tunnel.Config.Type),
+		URL:        tunnel.URL,
+		RemotePort: tunnel.RemotePort,
+		LocalPort:  tunnel.Config.LocalPort,
+		Upstream:   tunnel.Config.Upstream,
+	}
+	if stats, ok := s.client.TunnelStats(tunnel.ID); ok {
+		view.Stats = &statsView{
+			Requests:     stats.Requests,
+			RateLimited:  stats.RateLimited,
+			InFlight:     stats.InFlight,
+			PeakInFlight: stats.PeakInFlight,
+		}
+		if webhook := stats.Webhook; webhook != nil {
+			view.Stats.Webhook = &webhookView{
+				Provider: string(webhook.Provider),
+				Verified: webhook.Verified,
+				Rejected: webhook.Rejected,
+			}
+			if last := webhook.Last; last != nil {
+				view.Stats.Webhook.Last = &webhookResultView{
+					Method:   last.Method,
+					URL:      last.URL,
+					Verified: last.Verified,
+					Error:    last.Error,
+					Time:     last.Time,
+				}
+			}
+		}
+	}
+	if status, ok := s.client.FaultStatus(tunnel.ID); ok {
+		faults := newFaultsView(status)
+		view.Faults = &faults
+	}
+	return view
+}
+
+// newFaultsView describes the fault injection state of a tunnel
+func newFaultsView(status model.FaultStatus) faultsView {
+	view := faultsView{
+		Enabled:       status.Enabled,
+		Paths:         status.Config.Paths,
+		Probability:   status.Config.Probability,
+		ErrorRate:     status.Config.ErrorRate,
+		ErrorStatuses: status.Config.ErrorStatuses,
+		DropRate:      status.Config.DropRate,
+		Bandwidth:     status.Config.Bandwidth,
+		Delayed:       status.Delayed,
+		Errors:        status.Errors,
+		Dropped:       status.Dropped,
+	}
+	if status.Config.Latency > 0 {
+		view.Latency = status.Config.Latency.String()
+	}
+	if status.Config.Jitter > 0 {
+		view.Jitter = status.Config.Jitter.String()
+	}
+	return view
+}
+
+// newMocksView describes the mock rules of a tunnel
+func newMocksView(status model.MockStatus) mocksView {
+	view := mocksView{File: status.File, Rules: make([]mockRuleView, 0, len(status.Rules))}
+	for _, rule := range status.Rules {
+		view.Rules = append(view.Rules, mockRuleView{
+			Name:            rule.Name,
+			Method:          rule.Method,
+			Path:            rule.Path,
+			Headers:         rule.Headers,
+			Status:          rule.Status,
+			ResponseHeaders: rule.ResponseHeaders,
+			Body:            rule.Body,
+			File:            rule.File,
+		})
+	}
+	return view
+}
+
+// isMockFileType reports whether a Content-Type is YAML or JSON
+func isMockFileType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "application/json":
+		return true
+	}
+	return false
+}
+
+// requestHost returns the lower case host name of a Host header, without port and brackets
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
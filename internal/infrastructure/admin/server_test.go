@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/logger"
+)
+
+func TestServerChecksHost(t *testing.T) {
+	s := NewServer(nil, nil, logger.NewLogger(io.Discard, "error"))
+	addr, err := s.Start("127.0.0.2:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{"localhost", true},
+		{"localhost:4040", true},
+		{"LOCALHOST.", true},
+		{"127.0.0.1:4040", true},
+		{"[::1]:4040", true},
+		{addr, true},
+		{"127.0.0.2", true},
+		{"attacker.example.com", false},
+		{"attacker.example.com:4040", false},
+		{"localhost.attacker.example.com", false},
+		{"10.0.0.5:4040", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, req)
+
+			if allowed := rec.Code != http.StatusForbidden; allowed != tt.allowed {
+				t.Errorf("Host %q: status %d, allowed %v", tt.host, rec.Code, tt.allowed)
+			}
+		})
+	}
+}
@@ -56,6 +56,8 @@ func (r *ConfigRepository) Load(configPath string) (*model.Config, error) {
 	config.BaseDomain = viper.GetString("base_domain")
 	config.LogLevel = model.LogLevel(viper.GetString("log_level"))
 	config.LogFile = viper.GetString("log_file")
+	config.AdminAddress = viper.GetString("admin_address")
+	config.BodyMemoryBudget = viper.GetInt64("body_memory_budget")
 
 	// Muat tunnel
 	var tunnelConfigs []model.TunnelConfig
@@ -64,6 +66,13 @@ func (r *ConfigRepository) Load(configPath string) (*model.Config, error) {
 	}
 	config.Tunnels = tunnelConfigs
 
+	// Muat domain kustom
+	var domains []model.CustomDomain
+	if err := viper.UnmarshalKey("domains", &domains); err != nil {
+		return nil, fmt.Errorf("error parsing konfigurasi domain: %v", err)
+	}
+	config.Domains = domains
+
 	return config, nil
 }
 
@@ -100,7 +109,10 @@ func (r *ConfigRepository) Save(config *model.Config, configPath string) error {
 	viper.Set("base_domain", config.BaseDomain)
 	viper.Set("log_level", string(config.LogLevel))
 	viper.Set("log_file", config.LogFile)
+	viper.Set("admin_address", config.AdminAddress)
+	viper.Set("body_memory_budget", config.BodyMemoryBudget)
 	viper.Set("tunnels", config.Tunnels)
+	viper.Set("domains", config.Domains)
 
 	// Simpan ke file
 	if err := viper.WriteConfig(); err != nil {
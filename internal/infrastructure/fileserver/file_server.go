@@ -0,0 +1,232 @@
+package fileserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// indexFile is served for directories and as the SPA fallback
+const indexFile = "index.html"
+
+// Options configures a static file server
+type Options struct {
+	// Listing shows the contents of directories without an index.html
+	Listing bool
+	// SPA serves the root index.html for unknown paths, so that client side
+	// routers of single page apps can handle them
+	SPA bool
+}
+
+// Server serves the files of a directory on a local port. Range requests,
+// conditional requests and index.html files are handled by net/http.
+type Server struct {
+	root    string
+	options Options
+	fs      staticFileSystem
+	server  *http.Server
+}
+
+// New creates a file server for the directory root
+func New(root string, options Options) (*Server, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	// Symlinks are checked against the real path of the root
+	real, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		root:    abs,
+		options: options,
+		fs:      staticFileSystem{root: real, dir: http.Dir(real), listing: options.Listing},
+	}, nil
+}
+
+// Root returns the absolute path of the served directory
+func (s *Server) Root() string {
+	return s.root
+}
+
+// Handler returns the HTTP handler serving the directory
+func (s *Server) Handler() http.Handler {
+	fileServer := http.FileServer(s.fs)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if s.options.SPA && wantsSPAFallback(r) {
+			f, err := s.fs.Open(path.Clean("/" + r.URL.Path))
+			if errors.Is(err, fs.ErrNotExist) {
+				s.serveIndex(w, r)
+				return
+			}
+			if err == nil {
+				f.Close()
+			}
+		}
+
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// Start serves the directory on a random port of the loopback interface and
+// returns the port
+func (s *Server) Start() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+
+	s.server = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.server.Serve(listener)
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// Close stops the file server
+func (s *Server) Close() error {
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// serveIndex serves the root index.html for a path that does not exist
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	f, err := s.fs.Open("/" + indexFile)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, indexFile, info.ModTime(), f)
+}
+
+// wantsSPAFallback reports whether a request for a missing path should get
+// the SPA index: page navigations do, missing assets such as /app.js do not
+func wantsSPAFallback(r *http.Request) bool {
+	if path.Ext(r.URL.Path) != "" {
+		return false
+	}
+	accept := r.Header.Get("Accept")
+	return accept == "" || strings.Contains(accept, "text/html") || strings.Contains(accept, "*/*")
+}
+
+// staticFileSystem hides dot files such as .git and .env, symlinks that
+// point outside the served directory and, when listing is disabled,
+// directories without an index.html
+type staticFileSystem struct {
+	root    string
+	dir     http.Dir
+	listing bool
+}
+
+// Open opens a file of the served directory
+func (fsys staticFileSystem) Open(name string) (http.File, error) {
+	if hasDotSegment(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	f, err := fsys.open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !info.IsDir() {
+		return f, nil
+	}
+
+	if !fsys.listing {
+		index, err := fsys.open(path.Join(name, indexFile))
+		if err != nil {
+			f.Close()
+			return nil, fs.ErrNotExist
+		}
+		index.Close()
+	}
+	return dotFileHidingDir{f}, nil
+}
+
+// open opens a file whose real path, after following symlinks, is inside
+// the served directory. Other files are reported as missing.
+func (fsys staticFileSystem) open(name string) (http.File, error) {
+	real, err := filepath.EvalSymlinks(filepath.Join(fsys.root, filepath.FromSlash(path.Clean("/"+name))))
+	if err != nil {
+		return nil, err
+	}
+	if !isWithin(fsys.root, real) {
+		return nil, fs.ErrNotExist
+	}
+	return fsys.dir.Open(name)
+}
+
+// isWithin reports whether target is root or a path inside it
+func isWithin(root string, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dotFileHidingDir leaves dot files out of directory listings
+type dotFileHidingDir struct {
+	http.File
+}
+
+// Readdir returns the directory entries that are not dot files
+func (d dotFileHidingDir) Readdir(n int) ([]os.FileInfo, error) {
+	entries, err := d.File.Readdir(n)
+	visible := entries[:0]
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ".") {
+			visible = append(visible, entry)
+		}
+	}
+	return visible, err
+}
+
+// hasDotSegment reports whether any element of a slash separated path
+// starts with a dot
+func hasDotSegment(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package fileserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestServer creates a file server for a directory holding files
+func newTestServer(t *testing.T, files map[string]string, options Options) (*Server, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := New(root, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, root
+}
+
+// get requests a path from a file server
+func get(s *Server, target string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHasDotSegment(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"/", false},
+		{"/index.html", false},
+		{"/assets/app.v1.js", false},
+		{"/./index.html", false},
+		{"/.env", true},
+		{"/.git/config", true},
+		{"/app/.hidden/file", true},
+		{"/..", true},
+	}
+
+	for _, tt := range tests {
+		if got := hasDotSegment(tt.name); got != tt.want {
+			t.Errorf("hasDotSegment(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServerHidesDotFiles(t *testing.T) {
+	s, _ := newTestServer(t, map[string]string{
+		"readme.txt":  "hello",
+		".env":        "SECRET=1",
+		".git/config": "[core]",
+		"docs/.draft": "draft",
+	}, Options{Listing: true})
+
+	for _, target := range []string{"/.env", "/.git/config", "/.git/", "/docs/.draft"} {
+		if rec := get(s, target, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", target, rec.Code)
+		}
+	}
+
+	rec := get(s, "/", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "readme.txt") {
+		t.Fatalf("GET /: status %d, body %q; want a listing with readme.txt", rec.Code, rec.Body.String())
+	}
+	if body := rec.Body.String(); strings.Contains(body, ".env") || strings.Contains(body, ".git") {
+		t.Errorf("listing shows dot files: %q", body)
+	}
+	if body := get(s, "/docs/", "").Body.String(); strings.Contains(body, ".draft") {
+		t.Errorf("listing of /docs/ shows dot files: %q", body)
+	}
+}
+
+func TestServerListing(t *testing.T) {
+	files := map[string]string{
+		"files/a.txt":      "a",
+		"site/index.html":  "<h1>site</h1>",
+		"site/about.html":  "about",
+		"empty/.gitignore": "",
+	}
+
+	tests := []struct {
+		listing bool
+		target  string
+		want    int
+	}{
+		{true, "/files/", http.StatusOK},
+		{false, "/files/", http.StatusNotFound},
+		{false, "/files/a.txt", http.StatusOK},
+		{false, "/site/", http.StatusOK},
+		{false, "/empty/", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		s, _ := newTestServer(t, files, Options{Listing: tt.listing})
+		if rec := get(s, tt.target, ""); rec.Code != tt.want {
+			t.Errorf("listing %v, GET %s: status %d, want %d", tt.listing, tt.target, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestServerSPAFallback(t *testing.T) {
+	s, _ := newTestServer(t, map[string]string{
+		"index.html":    "<h1>app</h1>",
+		"assets/app.js": "app()",
+	}, Options{SPA: true})
+
+	tests := []struct {
+		name    string
+		target  string
+		accept  string
+		want    int
+		wantApp bool
+	}{
+		{"page navigation", "/dashboard/settings", "text/html,application/xhtml+xml", http.StatusOK, true},
+		{"request without Accept", "/dashboard", "", http.StatusOK, true},
+		{"wildcard Accept", "/dashboard", "*/*", http.StatusOK, true},
+		{"missing asset", "/assets/missing.js", "*/*", http.StatusNotFound, false},
+		{"missing page with extension", "/old.html", "text/html", http.StatusNotFound, false},
+		{"API request", "/api/users", "application/json", http.StatusNotFound, false},
+		{"existing asset", "/assets/app.js", "*/*", http.StatusOK, false},
+		{"dot file", "/.env", "text/html", http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(s, tt.target, tt.accept)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d", rec.Code, tt.want)
+			}
+			if app := rec.Body.String() == "<h1>app</h1>"; app != tt.wantApp {
+				t.Errorf("body %q, index served %v, want %v", rec.Body.String(), app, tt.wantApp)
+			}
+		})
+	}
+
+	s, _ = newTestServer(t, map[string]string{"app.js": "app()"}, Options{SPA: true})
+	if rec := get(s, "/dashboard", "text/html"); rec.Code != http.StatusNotFound {
+		t.Errorf("fallback without index.html: status %d, want 404", rec.Code)
+	}
+}
+
+func TestServerSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, root := newTestServer(t, map[string]string{"public/page.txt": "page"}, Options{Listing: true})
+
+	links := map[string]string{
+		"inside.txt":     filepath.Join(root, "public", "page.txt"),
+		"public-dir":     filepath.Join(root, "public"),
+		"escape.txt":     filepath.Join(outside, "secret.txt"),
+		"escape-dir":     outside,
+		"relative.txt":   filepath.Join("..", filepath.Base(outside), "secret.txt"),
+		"public/up":      "..",
+		"public/dangles": filepath.Join(root, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+	// The relative link needs both temporary directories in the same parent
+	sameParent := filepath.Dir(outside) == filepath.Dir(root)
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/inside.txt", http.StatusOK},
+		{"/public-dir/page.txt", http.StatusOK},
+		{"/public/up/public/page.txt", http.StatusOK},
+		{"/escape.txt", http.StatusNotFound},
+		{"/escape-dir/secret.txt", http.StatusNotFound},
+		{"/escape-dir/", http.StatusNotFound},
+		{"/relative.txt", http.StatusNotFound},
+		{"/public/dangles", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		if tt.target == "/relative.txt" && !sameParent {
+			continue
+		}
+		rec := get(s, tt.target, "")
+		if rec.Code != tt.want {
+			t.Errorf("GET %s: status %d, want %d", tt.target, rec.Code, tt.want)
+		}
+		if strings.Contains(rec.Body.String(), "secret") {
+			t.Errorf("GET %s served a file outside the root", tt.target)
+		}
+	}
+}
+
+func TestServerRejectsOtherMethods(t *testing.T) {
+	s, _ := newTestServer(t, map[string]string{"index.html": "home"}, Options{})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST /: status %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+}
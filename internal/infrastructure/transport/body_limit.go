@@ -0,0 +1,106 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+const (
+	// defaultMaxRequestBody is the largest request body forwarded by default
+	defaultMaxRequestBody = 100 << 20
+	// defaultMaxResponseBody is the largest response body returned by default
+	defaultMaxResponseBody = 100 << 20
+	// defaultBodyMemoryBudget is the default memory for bodies buffered at once
+	defaultBodyMemoryBudget = 512 << 20
+
+	// bodyReadChunk is how much of a response body is reserved and read at a time
+	bodyReadChunk = 32 << 10
+)
+
+var (
+	// errBodyTooLarge is returned for response bodies over the tunnel's limit
+	errBodyTooLarge = errors.New("response body exceeds the size limit")
+	// errMemoryBudget is returned when buffered bodies would exceed the memory budget
+	errMemoryBudget = errors.New("body memory budget exhausted")
+)
+
+// memoryBudget bounds the total size of the request and response bodies
+// buffered by all tunnels at once. Bodies are buffered in full until the
+// protocol supports streaming, so without a budget a few large concurrent
+// requests could exhaust the memory of the client.
+type memoryBudget struct {
+	limit int64
+	used  int64
+}
+
+// newMemoryBudget creates a memory budget of limit bytes (default 512 MiB)
+func newMemoryBudget(limit int64) *memoryBudget {
+	if limit <= 0 {
+		limit = defaultBodyMemoryBudget
+	}
+	return &memoryBudget{limit: limit}
+}
+
+// reservation returns an empty reservation against the budget
+func (b *memoryBudget) reservation() *bodyReservation {
+	return &bodyReservation{budget: b}
+}
+
+// bodyReservation is the part of the memory budget held by one request
+type bodyReservation struct {
+	budget *memoryBudget
+	size   int64
+}
+
+// grow reserves n more bytes, or returns errMemoryBudget when the budget
+// does not have them
+func (r *bodyReservation) grow(n int64) error {
+	for {
+		used := atomic.LoadInt64(&r.budget.used)
+		if used+n > r.budget.limit {
+			return errMemoryBudget
+		}
+		if atomic.CompareAndSwapInt64(&r.budget.used, used, used+n) {
+			r.size += n
+			return nil
+		}
+	}
+}
+
+// release returns all reserved bytes to the budget
+func (r *bodyReservation) release() {
+	atomic.AddInt64(&r.budget.used, -r.size)
+	r.size = 0
+}
+
+// readResponseBody reads an upstream response body of at most limit bytes,
+// reserving memory for it as it is read
+func readResponseBody(body io.Reader, contentLength int64, limit int64, reservation *bodyReservation) ([]byte, error) {
+	if contentLength > limit {
+		return nil, errBodyTooLarge
+	}
+
+	size := contentLength
+	if size < 0 {
+		size = 0
+	}
+	data := make([]byte, 0, size)
+	chunk := make([]byte, bodyReadChunk)
+	for {
+		if err := reservation.grow(bodyReadChunk); err != nil {
+			return nil, err
+		}
+		n, err := io.ReadFull(body, chunk)
+		data = append(data, chunk[:n]...)
+		if int64(len(data)) > limit {
+			return nil, errBodyTooLarge
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
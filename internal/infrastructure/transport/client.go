@@ -34,10 +34,20 @@ type Client struct {
 	subdomain    string 
 	config       *model.Config
 	userData     *model.AuthData 
+	httpTunnels  map[string]*httpTunnel
+	// faults holds the fault injectors of registered tunnels of any type
+	faults       map[string]*faultInjector
+	tunnelsMutex sync.RWMutex
+	// bodyBudget bounds the memory of request and response bodies buffered at once
+	bodyBudget *memoryBudget
+	// defaultHTTPTunnel serves requests for tunnels that are not registered by this client
+	defaultHTTPTunnel *httpTunnel
 }
 
 
 func NewClient(config *model.Config, logger port.Logger) *Client {
+	defaultHTTPTunnel, _ := newHTTPTunnel(model.TunnelConfig{Type: model.TunnelTypeHTTP})
+
 	return &Client{
 		serverAddr:   config.ServerAddress,
 		controlPort:  config.ControlPort,
@@ -53,6 +63,10 @@ func NewClient(config *model.Config, logger port.Logger) *Client {
 		logger:       logger,
 		handlers:     make(map[model.MessageType]func(*model.Message) error),
 		config:       config,
+		httpTunnels:  make(map[string]*httpTunnel),
+		faults:       make(map[string]*faultInjector),
+		bodyBudget:   newMemoryBudget(config.BodyMemoryBudget),
+		defaultHTTPTunnel: defaultHTTPTunnel,
 	}
 }
 
@@ -318,17 +332,43 @@ func (c *Client) readPump() {
 		c.mutex.Unlock()
 
 		if exists {
-			if err := handler(&msg); err != nil {
-				c.logger.Error("Error handling message %s: %v", msg.Type, err)
+			// HTTP requests are handled concurrently so that a slow local
+			// service does not hold up other requests and tunnel data
+			if msg.Type == model.MessageTypeHTTPRequest {
+				go c.handleMessage(handler, &msg)
+				continue
 			}
+			c.handleMessage(handler, &msg)
 		} else {
 			c.logger.Error("No handler for message type: %s", msg.Type)
 		}
 	}
 }
 
+// handleMessage runs the handler of a message and logs its error
+func (c *Client) handleMessage(handler func(*model.Message) error, msg *model.Message) {
+	if err := handler(msg); err != nil {
+		c.logger.Error("Error handling message %s: %v", msg.Type, err)
+	}
+}
+
 // SendRegisterTunnel sends a tunnel registration request to the server.
 func (c *Client) SendRegisterTunnel(config model.TunnelConfig) (*model.RegisterResponsePayload, error) {
+	var tunnel *httpTunnel
+	var faults *faultInjector
+	if config.Type == model.TunnelTypeHTTP {
+		var err error
+		if tunnel, err = newHTTPTunnel(config); err != nil {
+			return nil, fmt.Errorf("invalid HTTP tunnel configuration: %v", err)
+		}
+		faults = tunnel.faults
+	} else {
+		var err error
+		if faults, err = newFaultInjector(config.Faults); err != nil {
+			return nil, fmt.Errorf("invalid fault configuration: %v", err)
+		}
+	}
+
 	c.subdomain = config.Subdomain
 
 	responseCh := make(chan *model.RegisterResponsePayload, 1)
@@ -359,10 +399,11 @@ func (c *Client) SendRegisterTunnel(config model.TunnelConfig) (*model.RegisterR
 	payload := model.RegisterPayload{
 		TunnelType: string(config.Type),
 		Subdomain:  config.Subdomain,
+		Hostname:   config.Hostname,
 		LocalAddr:  config.LocalAddr,
 		LocalPort:  config.LocalPort,
 		RemotePort: config.RemotePort,
-		Auth:       config.Auth,
+		Auth:       serverAuth(config.Type, config.Auth),
 	}
 
 	msg, err := model.NewMessage(model.MessageTypeRegister, payload)
@@ -379,6 +420,15 @@ func (c *Client) SendRegisterTunnel(config model.TunnelConfig) (*model.RegisterR
 		if !response.Success {
 			return nil, fmt.Errorf("tunnel registration failed: %s", response.Error)
 		}
+		c.tunnelsMutex.Lock()
+		if tunnel != nil {
+			tunnel.start(c.logger)
+			c.httpTunnels[response.TunnelID] = tunnel
+		}
+		if faults != nil {
+			c.faults[response.TunnelID] = faults
+		}
+		c.tunnelsMutex.Unlock()
 		return response, nil
 	case err := <-errCh:
 		return nil, err
@@ -398,6 +448,14 @@ func (c *Client) SendUnregisterTunnel(tunnelID string) error {
 		return fmt.Errorf("gagal membuat pesan: %v", err)
 	}
 
+	c.tunnelsMutex.Lock()
+	if tunnel, ok := c.httpTunnels[tunnelID]; ok {
+		tunnel.close()
+		delete(c.httpTunnels, tunnelID)
+	}
+	delete(c.faults, tunnelID)
+	c.tunnelsMutex.Unlock()
+
 	return c.sendMessage(msg)
 }
 
@@ -418,6 +476,53 @@ func (c *Client) SendData(tunnelID string, connectionID string, data []byte) err
 }
 
 
+// SendClose tells the server that a connection of a tunnel has ended
+func (c *Client) SendClose(tunnelID string, connectionID string) error {
+	payload := model.ClosePayload{
+		TunnelID:     tunnelID,
+		ConnectionID: connectionID,
+	}
+
+	msg, err := model.NewMessage(model.MessageTypeClose, payload)
+	if err != nil {
+		return fmt.Errorf("gagal membuat pesan: %v", err)
+	}
+
+	return c.sendMessage(msg)
+}
+
+// SendDatagram sends a datagram of a UDP tunnel session to the server
+func (c *Client) SendDatagram(tunnelID string, sessionID string, data []byte) error {
+	payload := model.DatagramPayload{
+		TunnelID:  tunnelID,
+		SessionID: sessionID,
+		Data:      data,
+	}
+
+	msg, err := model.NewMessage(model.MessageTypeDatagram, payload)
+	if err != nil {
+		return fmt.Errorf("gagal membuat pesan: %v", err)
+	}
+
+	return c.sendMessage(msg)
+}
+
+// SendDatagramClose tells the server that a UDP tunnel session has ended
+func (c *Client) SendDatagramClose(tunnelID string, sessionID string) error {
+	payload := model.DatagramClosePayload{
+		TunnelID:  tunnelID,
+		SessionID: sessionID,
+	}
+
+	msg, err := model.NewMessage(model.MessageTypeDatagramClose, payload)
+	if err != nil {
+		return fmt.Errorf("gagal membuat pesan: %v", err)
+	}
+
+	return c.sendMessage(msg)
+}
+
+
 func (c *Client) GetSubdomain() string {
 	return c.subdomain
 }
@@ -428,6 +533,107 @@ func (c *Client) SetSubdomain(subdomain string) {
 }
 
 
+// httpTunnel returns the HTTP tunnel a request belongs to by its tunnel ID.
+// Requests for other tunnel IDs go to the default tunnel forwarding to the
+// request's port, unless a registered tunnel restricts access: the request
+// may be meant for it, so nil is returned and the request must be rejected.
+func (c *Client) httpTunnel(request *model.HTTPRequest) *httpTunnel {
+	c.tunnelsMutex.RLock()
+	defer c.tunnelsMutex.RUnlock()
+
+	if tunnel, ok := c.httpTunnels[request.TunnelID]; ok {
+		return tunnel
+	}
+	for _, tunnel := range c.httpTunnels {
+		if tunnel.restricted() {
+			return nil
+		}
+	}
+
+	return c.defaultHTTPTunnel
+}
+
+
+// TunnelStats returns the request counters of a registered HTTP tunnel
+func (c *Client) TunnelStats(tunnelID string) (model.TunnelStats, bool) {
+	c.tunnelsMutex.RLock()
+	defer c.tunnelsMutex.RUnlock()
+
+	tunnel, ok := c.httpTunnels[tunnelID]
+	if !ok {
+		return model.TunnelStats{}, false
+	}
+	return tunnel.statsSnapshot(), true
+}
+
+
+// FaultStatus returns the fault injection state of a registered tunnel
+func (c *Client) FaultStatus(tunnelID string) (model.FaultStatus, bool) {
+	faults := c.tunnelFaults(tunnelID)
+	if faults == nil {
+		return model.FaultStatus{}, false
+	}
+	return faults.status(), true
+}
+
+// SetFaultsEnabled turns fault injection of a registered tunnel on or off at runtime
+func (c *Client) SetFaultsEnabled(tunnelID string, enabled bool) (model.FaultStatus, bool) {
+	faults := c.tunnelFaults(tunnelID)
+	if faults == nil {
+		return model.FaultStatus{}, false
+	}
+	faults.setEnabled(enabled)
+	if enabled {
+		c.logger.Info("Fault injection for tunnel %s turned on", tunnelID)
+	} else {
+		c.logger.Info("Fault injection for tunnel %s turned off", tunnelID)
+	}
+	return faults.status(), true
+}
+
+// MockRules returns the mock file and rules of a registered HTTP tunnel
+func (c *Client) MockRules(tunnelID string) (model.MockStatus, bool) {
+	mocks := c.tunnelMocks(tunnelID)
+	if mocks == nil {
+		return model.MockStatus{}, false
+	}
+	return mocks.status(), true
+}
+
+// SetMockRules checks the YAML mock rules in data, saves them to the mock
+// file of a registered HTTP tunnel and uses them at once. It returns false
+// when the tunnel has no mock file.
+func (c *Client) SetMockRules(tunnelID string, data []byte) (model.MockStatus, bool, error) {
+	mocks := c.tunnelMocks(tunnelID)
+	if mocks == nil {
+		return model.MockStatus{}, false, nil
+	}
+	if err := mocks.replace(data); err != nil {
+		return model.MockStatus{}, true, err
+	}
+	status := mocks.status()
+	c.logger.Info("Mock rules of tunnel %s replaced through the admin API (%d rules)", tunnelID, len(status.Rules))
+	return status, true, nil
+}
+
+// tunnelMocks returns the mock rules of an HTTP tunnel, or nil when it has none
+func (c *Client) tunnelMocks(tunnelID string) *mockRules {
+	c.tunnelsMutex.RLock()
+	defer c.tunnelsMutex.RUnlock()
+	if tunnel, ok := c.httpTunnels[tunnelID]; ok {
+		return tunnel.mocks
+	}
+	return nil
+}
+
+// tunnelFaults returns the fault injector of a tunnel, or nil when it has none
+func (c *Client) tunnelFaults(tunnelID string) *faultInjector {
+	c.tunnelsMutex.RLock()
+	defer c.tunnelsMutex.RUnlock()
+	return c.faults[tunnelID]
+}
+
+
 func (c *Client) GetUserData() *model.AuthData {
 	return c.userData
 }
@@ -449,4 +655,23 @@ func (c *Client) CheckTunnelLimit() (bool, int, int) {
 }
 
 
+// CheckCustomDomains returns an error when the subscription of the user does
+// not include custom domains. Without authentication the server decides.
+func (c *Client) CheckCustomDomains() error {
+	if c.userData == nil {
+		return nil
+	}
+
+	if !c.userData.Subscription.Features.CustomDomains {
+		return fmt.Errorf("custom domains are not included in your %s subscription", c.userData.Subscription.Name)
+	}
+	return nil
+}
+
+// BaseDomain returns the domain under which the server hosts tunnels
+func (c *Client) BaseDomain() string {
+	return c.baseDomain
+}
+
+
 var _ port.Client = (*Client)(nil)
@@ -2,12 +2,13 @@ package transport
 
 import (
 	"bytes"
-	"fmt"
-	"io"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 // HandleHTTPRequestMessage menangani pesan permintaan HTTP dari server
@@ -21,18 +22,142 @@ func (c *Client) HandleHTTPRequestMessage(msg *model.Message) error {
 
 	c.logger.Info("Menerima permintaan HTTP: %s %s", request.Method, request.URL)
 
+	// Tolak permintaan untuk tunnel yang tidak dikenal agar tidak melewati autentikasi dan kebijakan tunnel lain
+	tunnel := c.httpTunnel(request)
+	if tunnel == nil {
+		c.logger.Warn("Permintaan %s %s ditolak: tunnel %s tidak terdaftar di client ini", request.Method, request.URL, request.TunnelID)
+		return c.sendHTTPErrorPage(request, c.defaultHTTPTunnel, http.StatusBadGateway, nil)
+	}
+	defer tunnel.stats.begin()()
+
+	// Tolak pengunjung dari alamat yang tidak diizinkan kebijakan IP tunnel
+	if err := tunnel.policy.check(request.RemoteAddr); err != nil {
+		c.logger.Warn("Permintaan %s %s ditolak oleh kebijakan IP: %v", request.Method, request.URL, err)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusForbidden, nil)
+	}
+
+	// Batasi laju dan jumlah permintaan bersamaan sebelum menghubungi layanan lokal
+	done, retryAfter, ok := tunnel.limiter.acquire(request.RemoteAddr)
+	if !ok {
+		atomic.AddInt64(&tunnel.stats.rateLimited, 1)
+		c.logger.Warn("Permintaan %s %s dari %s ditolak: batas laju terlampaui", request.Method, request.URL, request.RemoteAddr)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusTooManyRequests, http.Header{"Retry-After": {retryAfterSeconds(retryAfter)}})
+	}
+	defer done()
+
+	// Tolak body permintaan yang melebihi batas ukuran tunnel
+	if int64(len(request.Body)) > tunnel.maxRequestBody {
+		c.logger.Warn("Permintaan %s %s ditolak: body %d byte melebihi batas %d byte", request.Method, request.URL, len(request.Body), tunnel.maxRequestBody)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusRequestEntityTooLarge, nil)
+	}
+
+	// Catat body yang ditampung di anggaran memori bersama semua tunnel
+	reservation := c.bodyBudget.reservation()
+	defer reservation.release()
+	if err := reservation.grow(int64(len(request.Body))); err != nil {
+		c.logger.Warn("Permintaan %s %s ditolak: %v", request.Method, request.URL, err)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusServiceUnavailable, http.Header{"Retry-After": {"1"}})
+	}
+
+	// Jawab preflight CORS langsung karena browser tidak mengirim kredensial pada preflight
+	if tunnel.cors.isPreflight(request) {
+		headers, ok := tunnel.cors.preflight(request.Headers)
+		if !ok {
+			c.logger.Warn("Preflight CORS %s dari origin %s ditolak", request.URL, request.Headers.Get("Origin"))
+			return c.sendHTTPStatusResponse(request.ID, http.StatusForbidden, headers)
+		}
+		return c.sendHTTPResponse(&model.HTTPResponse{
+			ID:         request.ID,
+			StatusCode: http.StatusNoContent,
+			Headers:    headers,
+		})
+	}
+
+	// Verifikasi autentikasi tunnel sebelum meneruskan permintaan
+	if ok, challenge := authorizeRequest(tunnel.config.Auth, request.Headers); !ok {
+		c.logger.Warn("Permintaan %s %s dari %s ditolak: autentikasi tidak valid", request.Method, request.URL, request.RemoteAddr)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusUnauthorized, challenge)
+	}
+
+	// Verifikasi token JWT dan simpan klaimnya untuk diteruskan ke layanan lokal
+	var claims jwt.MapClaims
+	if tunnel.jwt != nil {
+		claims, err = tunnel.jwt.verify(request.Headers)
+		if err != nil {
+			c.logger.Warn("Permintaan %s %s dari %s ditolak: token JWT tidak valid: %v", request.Method, request.URL, request.RemoteAddr, err)
+			return c.sendHTTPErrorPage(request, tunnel, http.StatusUnauthorized, bearerChallenge(err))
+		}
+	}
+
+	// Verifikasi tanda tangan webhook sebelum meneruskan permintaan
+	if tunnel.webhook != nil {
+		publicURL := publicScheme(request) + "://" + c.publicHost(request) + request.URL
+		if err := tunnel.webhook.verify(request, publicURL); err != nil {
+			c.logger.Warn("Webhook %s %s dari %s ditolak: verifikasi tanda tangan %s gagal: %v", request.Method, request.URL, request.RemoteAddr, tunnel.webhook.provider, err)
+			return c.sendHTTPErrorPage(request, tunnel, http.StatusUnauthorized, nil)
+		}
+		c.logger.Info("Tanda tangan webhook %s valid untuk %s %s", tunnel.webhook.provider, request.Method, request.URL)
+	}
+
+	// Login OIDC pengunjung ditangani langsung oleh client
+	var identity *oidcIdentity
+	if tunnel.oidc != nil {
+		var status int
+		var headers http.Header
+		identity, status, headers, err = tunnel.oidc.authenticate(request, publicScheme(request)+"://"+c.publicHost(request))
+		if err != nil {
+			c.logger.Warn("Login OIDC untuk %s %s gagal: %v", request.Method, request.URL, err)
+		}
+		if identity == nil {
+			return c.sendHTTPErrorPage(request, tunnel, status, headers)
+		}
+	}
+
+	// Suntikkan gangguan (latency, error, permintaan tanpa respons) untuk uji ketahanan
+	fault, faulty := tunnel.faults.plan(request.URL)
+	if faulty {
+		if fault.delay > 0 {
+			c.logger.Info("[FAULT] %s %s ditunda %s", request.Method, request.URL, fault.delay)
+			time.Sleep(fault.delay)
+		}
+		if fault.drop {
+			c.logger.Info("[FAULT] %s %s diputus tanpa respons", request.Method, request.URL)
+			return nil
+		}
+		if fault.status != 0 {
+			c.logger.Info("[FAULT] %s %s dijawab dengan error %d", request.Method, request.URL, fault.status)
+			return c.sendHTTPErrorPage(request, tunnel, fault.status, nil)
+		}
+	}
+
+	// Jawab permintaan yang cocok dengan aturan mock tanpa menghubungi layanan lokal
+	if rule := tunnel.mocks.match(request); rule != nil {
+		response, err := rule.response(request.ID)
+		if err != nil {
+			c.logger.Error("Gagal membuat respons mock %s: %v", rule.name, err)
+			return c.sendHTTPErrorPage(request, tunnel, http.StatusInternalServerError, nil)
+		}
+		c.logger.Info("[MOCK] %s %s dijawab oleh aturan mock %s dengan status %d", request.Method, request.URL, rule.name, response.StatusCode)
+		tunnel.cors.decorate(response.Headers, request.Headers.Get("Origin"))
+		return c.sendHTTPResponse(response)
+	}
+
+	// Tentukan layanan lokal tujuan berdasarkan tabel route tunnel
+	target, requestURI, release, err := tunnel.resolve(request.URL, request.Headers, request.LocalPort)
+	if err != nil {
+		c.logger.Error("Gagal menentukan layanan lokal tujuan: %v", err)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusBadGateway, nil)
+	}
+	defer release()
+
 	// Buat permintaan HTTP ke layanan lokal di komputer klien
-	// Selalu gunakan HTTP untuk koneksi lokal, terlepas dari skema yang diterima dari server
-	// Ini karena layanan lokal biasanya hanya mendukung HTTP
-	scheme := "http"
-	
-	// Gunakan localhost di komputer klien, bukan di server
-	targetURL := fmt.Sprintf("%s://localhost:%d%s", scheme, request.LocalPort, request.URL)
+	// Skema (http atau https) ditentukan oleh konfigurasi tunnel
+	targetURL := target + requestURI
 	c.logger.Info("Mengirim permintaan ke layanan lokal: %s", targetURL)
 	httpReq, err := http.NewRequest(request.Method, targetURL, bytes.NewReader(request.Body))
 	if err != nil {
 		c.logger.Error("Gagal membuat permintaan HTTP: %v", err)
-		return c.sendHTTPErrorResponse(request.ID, err)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusInternalServerError, nil)
 	}
 
 	// Salin header
@@ -42,98 +167,132 @@ func (c *Client) HandleHTTPRequestMessage(msg *model.Message) error {
 		}
 	}
 
+	// Teruskan trailer permintaan; body dikirim chunked agar trailer juga sampai melalui HTTP/1.1
+	if len(request.Trailers) > 0 {
+		httpReq.Trailer = request.Trailers.Clone()
+		httpReq.ContentLength = -1
+	}
+
+	// Teruskan identitas pengunjung OIDC dan hapus cookie sesi client
+	if tunnel.oidc != nil {
+		setIdentityHeaders(httpReq.Header, identity)
+		removeCookies(httpReq.Header, oidcSessionCookie, oidcStateCookie)
+	}
+
+	// Beri tahu layanan lokal bahwa tanda tangan webhook sudah diverifikasi
+	httpReq.Header.Del(webhookVerifiedHeader)
+	if tunnel.webhook != nil {
+		httpReq.Header.Set(webhookVerifiedHeader, string(tunnel.webhook.provider))
+	}
+
+	// Teruskan klaim JWT yang dipilih sebagai header
+	if tunnel.jwt != nil {
+		setClaimHeaders(httpReq.Header, tunnel.config.Auth.ClaimHeaders, claims)
+	}
+
 	// Tambahkan header X-Forwarded-*
 	httpReq.Header.Set("X-Forwarded-Host", request.Headers.Get("Host"))
-	httpReq.Header.Set("X-Forwarded-Proto", scheme) // Gunakan skema yang diterima dari server
+	httpReq.Header.Set("X-Forwarded-Proto", publicScheme(request)) // Gunakan skema yang diterima dari server
 	httpReq.Header.Set("X-Forwarded-For", request.RemoteAddr)
 
+	// Sesuaikan header Host sesuai mode host header tunnel
+	applyHostHeader(httpReq, tunnel.config.HostHeader, request.Headers.Get("Host"))
+
 	// Kirim permintaan ke layanan lokal melalui koneksi balik
 	c.logger.Info("Membuat koneksi HTTP ke layanan lokal dengan metode %s", request.Method)
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := tunnel.do(httpReq, c.logger)
 	if err != nil {
 		c.logger.Error("Gagal mengirim permintaan HTTP ke layanan lokal: %v", err)
-		return c.sendHTTPErrorResponse(request.ID, err)
+		return c.sendHTTPErrorPage(request, tunnel, upstreamErrorStatus(err), nil)
 	}
 	c.logger.Info("Berhasil terhubung ke layanan lokal, status: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
 	defer resp.Body.Close()
 
-	// Baca body respons
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	// Baca body respons dalam batas ukuran tunnel dan anggaran memori
+	body, err := readResponseBody(resp.Body, resp.ContentLength, tunnel.maxResponseBody, reservation)
+	switch {
+	case err == errBodyTooLarge:
+		c.logger.Error("Respons %s %s ditolak: body melebihi batas %d byte", request.Method, request.URL, tunnel.maxResponseBody)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusBadGateway, nil)
+	case err == errMemoryBudget:
+		c.logger.Error("Respons %s %s ditolak: %v", request.Method, request.URL, err)
+		return c.sendHTTPErrorPage(request, tunnel, http.StatusServiceUnavailable, http.Header{"Retry-After": {"1"}})
+	case err != nil:
 		c.logger.Error("Gagal membaca body respons: %v", err)
-		return c.sendHTTPErrorResponse(request.ID, err)
-	}
-
-	// Periksa Content-Type untuk menentukan apakah ini adalah HTML
-	contentType := resp.Header.Get("Content-Type")
-	if strings.Contains(contentType, "text/html") {
-		// Ganti URL lokal dengan URL tunnel dalam respons HTML
-		localURLPrefix := fmt.Sprintf("http://localhost:%d", request.LocalPort)
-		localURLPrefixSecure := fmt.Sprintf("https://localhost:%d", request.LocalPort)
-		
-		// Buat URL tunnel berdasarkan skema yang diterima
-		tunnelScheme := "http"
-		if request.Scheme == "https" {
-			tunnelScheme = "https"
-		}
-		
-		// Ekstrak hostname yang tepat dari header Host
-		hostname := ""
-		if host, ok := request.Headers["Host"]; ok && len(host) > 0 {
-			hostname = host[0]
-		}
-		
-		// Jika hostname masih kosong, gunakan X-Forwarded-Host
-		if hostname == "" {
-			if host, ok := request.Headers["X-Forwarded-Host"]; ok && len(host) > 0 {
-				hostname = host[0]
-			}
-		}
-		
-		// Jika hostname masih kosong, ekstrak subdomain dari URL tunnel
-		if hostname == "" {
-			// Coba dapatkan subdomain dari URL yang diberikan oleh pengguna
-			subdomain := c.GetSubdomain()
-			if subdomain != "" {
-				hostname = subdomain + ".haxorport.online"
+		return c.sendHTTPErrorPage(request, tunnel, upstreamErrorStatus(err), nil)
+	}
+
+	// Sesuaikan header redirect dan cookie, serta URL lokal dalam body, jika diaktifkan untuk tunnel ini
+	if tunnel.config.RewriteHeaders || tunnel.config.RewriteURLs {
+		localHost := upstreamDisplayHost(strings.SplitN(target, "://", 2)[1])
+		hostname := c.publicHost(request)
+		c.logger.Info("Menggunakan hostname: %s untuk penggantian URL", hostname)
+
+		rewriter := newURLRewriter(publicScheme(request), hostname, localHost, httpReq.Host)
+		rewriter.rewriteHeaders(resp.Header)
+
+		if tunnel.config.RewriteURLs {
+			rewritten, err := rewriter.rewriteBody(resp.Header, body, tunnel.maxResponseBody, reservation)
+			if err != nil {
+				// Kirim body asli jika penggantian URL gagal
+				c.logger.Warn("Gagal mengganti URL dalam respons: %v", err)
 			} else {
-				// Fallback ke tunnel ID jika subdomain tidak tersedia
-				hostname = request.TunnelID + ".haxorport.online"
+				body = rewritten
 			}
 		}
-		
-		c.logger.Info(fmt.Sprintf("Menggunakan hostname: %s untuk penggantian URL", hostname))
-		tunnelURLPrefix := fmt.Sprintf("%s://%s", tunnelScheme, hostname)
-		
-		// Ganti URL dalam body
-		bodyStr := string(body)
-		bodyStr = strings.ReplaceAll(bodyStr, localURLPrefix, tunnelURLPrefix)
-		bodyStr = strings.ReplaceAll(bodyStr, localURLPrefixSecure, tunnelURLPrefix)
-		
-		// Ganti URL relatif dalam href dan src
-		// Contoh: href="/path" menjadi href="https://subdomain.haxorport.online/path"
-		bodyStr = strings.ReplaceAll(bodyStr, "href=\"/", "href=\""+tunnelURLPrefix+"/")
-		bodyStr = strings.ReplaceAll(bodyStr, "src=\"/", "src=\""+tunnelURLPrefix+"/")
-		
-		// Update body dengan konten yang telah dimodifikasi
-		body = []byte(bodyStr)
-		
-		c.logger.Info("URL lokal dalam respons HTML diganti dengan URL tunnel")
 	}
 
+	// Batasi bandwidth respons jika gangguan throttling aktif
+	if faulty && fault.throttle {
+		wait := tunnel.faults.transferTime(len(body))
+		c.logger.Info("[FAULT] %s %s dibatasi bandwidth, %d byte dikirim dalam %s", request.Method, request.URL, len(body), wait)
+		time.Sleep(wait)
+	}
+
+	// Ganti header CORS layanan lokal dengan kebijakan CORS tunnel
+	tunnel.cors.decorate(resp.Header, request.Headers.Get("Origin"))
+
 	// Buat respons HTTP
 	httpResp := &model.HTTPResponse{
 		ID:         request.ID,
 		StatusCode: resp.StatusCode,
 		Headers:    resp.Header,
 		Body:       body,
+		Trailers:   responseTrailers(resp.Trailer),
 	}
 
 	// Kirim respons ke server
 	return c.sendHTTPResponse(httpResp)
 }
 
+// publicHost mengembalikan hostname publik tunnel untuk sebuah permintaan
+func (c *Client) publicHost(request *model.HTTPRequest) string {
+	// Ekstrak hostname yang tepat dari header Host
+	if host := request.Headers.Get("Host"); host != "" {
+		return host
+	}
+
+	// Jika hostname masih kosong, gunakan X-Forwarded-Host
+	if host := request.Headers.Get("X-Forwarded-Host"); host != "" {
+		return host
+	}
+
+	// Jika hostname masih kosong, gunakan subdomain atau tunnel ID dengan domain dasar
+	subdomain := c.GetSubdomain()
+	if subdomain == "" {
+		subdomain = request.TunnelID
+	}
+	return subdomain + "." + c.baseDomain
+}
+
+// publicScheme mengembalikan skema yang digunakan pengunjung untuk mengakses tunnel
+func publicScheme(request *model.HTTPRequest) string {
+	if request.Scheme == "https" {
+		return "https"
+	}
+	return "http"
+}
+
 // sendHTTPResponse mengirim respons HTTP ke server
 func (c *Client) sendHTTPResponse(response *model.HTTPResponse) error {
 	// Buat pesan respons HTTP
@@ -147,16 +306,64 @@ func (c *Client) sendHTTPResponse(response *model.HTTPResponse) error {
 	return c.sendMessage(msg)
 }
 
-// sendHTTPErrorResponse mengirim respons HTTP error ke server
-func (c *Client) sendHTTPErrorResponse(requestID string, err error) error {
-	// Buat respons HTTP error
+// sendHTTPStatusResponse mengirim respons HTTP dengan kode status dan body teks standar ke server
+func (c *Client) sendHTTPStatusResponse(requestID string, statusCode int, headers http.Header) error {
+	if headers == nil {
+		headers = http.Header{}
+	}
+	headers.Set("Content-Type", "text/plain; charset=utf-8")
+
 	httpResp := &model.HTTPResponse{
 		ID:         requestID,
-		StatusCode: http.StatusInternalServerError,
-		Headers:    http.Header{},
-		Error:      err.Error(),
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       []byte(http.StatusText(statusCode) + "\n"),
 	}
 
 	// Kirim respons ke server
 	return c.sendHTTPResponse(httpResp)
 }
+
+// sendHTTPErrorPage mengirim halaman error (HTML atau JSON) untuk permintaan yang dijawab langsung oleh client.
+// Detail error internal hanya dicatat di log dan tidak dikirim ke pengunjung.
+func (c *Client) sendHTTPErrorPage(request *model.HTTPRequest, tunnel *httpTunnel, statusCode int, headers http.Header) error {
+	if statusCode < 400 {
+		return c.sendHTTPStatusResponse(request.ID, statusCode, headers)
+	}
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	data := newErrorPageData(statusCode, tunnel.displayName(c.publicHost(request)), request.ID, headers.Get("Retry-After"))
+	body, contentType := tunnel.pages.render(data, request.Headers.Get("Accept"))
+	headers.Set("Content-Type", contentType)
+	headers.Set("Cache-Control", "no-store")
+	tunnel.cors.decorate(headers, request.Headers.Get("Origin"))
+
+	httpResp := &model.HTTPResponse{
+		ID:         request.ID,
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       body,
+	}
+
+	// Kirim respons ke server
+	return c.sendHTTPResponse(httpResp)
+}
+
+// responseTrailers mengembalikan trailer respons yang benar-benar dikirim
+// layanan lokal. Trailer yang diumumkan tetapi tidak dikirim bernilai kosong
+// dan tidak diteruskan.
+func responseTrailers(trailer http.Header) http.Header {
+	var trailers http.Header
+	for key, values := range trailer {
+		if len(values) == 0 {
+			continue
+		}
+		if trailers == nil {
+			trailers = http.Header{}
+		}
+		trailers[key] = values
+	}
+	return trailers
+}
@@ -0,0 +1,105 @@
+package transport
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// localPort returns the port of a local test server
+func localPort(t *testing.T, server *httptest.Server) int {
+	t.Helper()
+	return server.Listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestHTTPRedirectsAreRewritten(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/login" {
+			w.Write([]byte("dashboard"))
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Domain: "localhost", Path: "/"})
+		// The Host header sent by the client is the local address, localhost:N
+		http.Redirect(w, r, "http://"+r.Host+"/dashboard", http.StatusFound)
+	}))
+	defer local.Close()
+
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	port := localPort(t, local)
+	if _, err := repo.client.SendRegisterTunnel(model.TunnelConfig{Type: model.TunnelTypeHTTP, LocalPort: port, RewriteHeaders: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := server.roundTrip(repo.client, &model.HTTPRequest{
+		ID:       "login",
+		TunnelID: "tunnel-http",
+		Method:   http.MethodPost,
+		URL:      "/login",
+		Headers:  http.Header{"Host": {"app.haxorport.online"}},
+		Scheme:   "https",
+	})
+
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("status = %d, want %d; the client followed the redirect", response.StatusCode, http.StatusFound)
+	}
+	if location := response.Headers.Get("Location"); location != "https://app.haxorport.online/dashboard" {
+		t.Errorf("Location = %q, want https://app.haxorport.online/dashboard", location)
+	}
+	if cookie := response.Headers.Get("Set-Cookie"); cookie != "session=abc; Path=/; Domain=app.haxorport.online" {
+		t.Errorf("Set-Cookie = %q, want the session cookie on app.haxorport.online", cookie)
+	}
+}
+
+func TestHTTPRequestsForUnknownTunnels(t *testing.T) {
+	var hits int32
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte("secret"))
+	}))
+	defer local.Close()
+	port := localPort(t, local)
+
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	request := func(id string, tunnelID string) *model.HTTPRequest {
+		return &model.HTTPRequest{ID: id, TunnelID: tunnelID, Method: http.MethodGet, URL: "/", LocalPort: port, Headers: http.Header{"Host": {"app.haxorport.online"}}}
+	}
+
+	// Without restricted tunnels, requests of other tunnels are forwarded to their port
+	if response := server.roundTrip(repo.client, request("open", "other")); response.StatusCode != http.StatusOK {
+		t.Errorf("status without registered tunnels = %d, want %d", response.StatusCode, http.StatusOK)
+	}
+
+	if _, err := repo.client.SendRegisterTunnel(model.TunnelConfig{
+		Type:      model.TunnelTypeHTTP,
+		LocalPort: port,
+		Auth:      &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "admin", Password: "s3cret"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		tunnelID string
+		want     int
+	}{
+		{"registered tunnel", "tunnel-http", http.StatusUnauthorized},
+		{"unknown tunnel on the same port", "other", http.StatusBadGateway},
+		{"no tunnel ID", "", http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := server.roundTrip(repo.client, request(tt.name, tt.tunnelID)); response.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.want)
+			}
+		})
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("local service got %d requests, want only the one sent before the auth tunnel was registered", n)
+	}
+}
@@ -0,0 +1,140 @@
+package transport
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/logger"
+	"github.com/gorilla/websocket"
+)
+
+// fakeServer is a control server that accepts every tunnel registration and
+// records the other messages sent by the client
+type fakeServer struct {
+	t        *testing.T
+	server   *httptest.Server
+	messages chan *model.Message
+
+	mutex sync.Mutex
+	conn  *websocket.Conn
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	s := &fakeServer{t: t, messages: make(chan *model.Message, 64)}
+	upgrader := websocket.Upgrader{}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.conn = conn
+		s.mutex.Unlock()
+
+		for {
+			var msg model.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Type == model.MessageTypeRegister {
+				var payload model.RegisterPayload
+				msg.ParsePayload(&payload)
+				s.send(model.MessageTypeRegister, model.RegisterResponsePayload{
+					Success:    true,
+					TunnelID:   "tunnel-" + payload.TunnelType,
+					RemotePort: 40000,
+				})
+				continue
+			}
+			s.messages <- &msg
+		}
+	}))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// send sends a message to the client
+func (s *fakeServer) send(msgType model.MessageType, payload interface{}) {
+	s.t.Helper()
+
+	msg, err := model.NewMessage(msgType, payload)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.conn.WriteJSON(msg); err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// expect waits for the next message of the given type sent by the client
+func (s *fakeServer) expect(msgType model.MessageType, payload interface{}) {
+	s.t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-s.messages:
+			if msg.Type != msgType {
+				continue
+			}
+			if err := json.Unmarshal(msg.Payload, payload); err != nil {
+				s.t.Fatal(err)
+			}
+			return
+		case <-timeout:
+			s.t.Fatalf("no %s message from the client", msgType)
+		}
+	}
+}
+
+// newTestRepository returns a tunnel repository whose client is connected to server
+func newTestRepository(t *testing.T, server *fakeServer) *TunnelRepository {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(server.server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	controlPort, _ := strconv.Atoi(port)
+
+	config := &model.Config{ServerAddress: host, ControlPort: controlPort}
+	client := NewClient(config, logger.NewLogger(io.Discard, "error"))
+	repo := NewTunnelRepository(client, client.logger)
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return repo
+}
+
+// roundTrip hands request to the client as if the server had sent it and
+// returns the response the client sends back
+func (s *fakeServer) roundTrip(client *Client, request *model.HTTPRequest) *model.HTTPResponse {
+	s.t.Helper()
+
+	msg, err := model.NewHTTPRequestMessage(request)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if err := client.HandleHTTPRequestMessage(msg); err != nil {
+		s.t.Fatal(err)
+	}
+	var payload model.HTTPResponsePayload
+	s.expect(model.MessageTypeHTTPResponse, &payload)
+	if payload.Response == nil || payload.Response.ID != request.ID {
+		s.t.Fatalf("response = %+v, want the response to %s", payload.Response, request.ID)
+	}
+	return payload.Response
+}
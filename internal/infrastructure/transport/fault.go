@@ -0,0 +1,213 @@
+package transport
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// defaultFaultStatuses are the statuses of injected errors when none are configured
+var defaultFaultStatuses = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// faultInjector injects latency, errors, dropped connections and throttling
+// into the traffic of a tunnel. It can be turned on and off at runtime.
+type faultInjector struct {
+	config   model.FaultConfig
+	paths    []*regexp.Regexp
+	statuses []int
+	enabled  int32
+
+	mutex sync.Mutex
+	rand  *rand.Rand
+
+	delayed int64
+	errors  int64
+	dropped int64
+}
+
+// faultPlan is the set of faults injected into a single HTTP request
+type faultPlan struct {
+	delay    time.Duration
+	status   int
+	drop     bool
+	throttle bool
+}
+
+// newFaultInjector creates the fault injector of a tunnel. It returns nil
+// when the tunnel has no fault injection; a nil injector injects nothing.
+func newFaultInjector(config *model.FaultConfig) (*faultInjector, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	for name, p := range map[string]float64{"probability": config.Probability, "error rate": config.ErrorRate, "drop rate": config.DropRate} {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("fault %s must be between 0 and 1", name)
+		}
+	}
+	if config.Latency < 0 || config.Jitter < 0 || config.Bandwidth < 0 {
+		return nil, fmt.Errorf("fault latency, jitter and bandwidth must not be negative")
+	}
+
+	f := &faultInjector{
+		config:   *config,
+		statuses: config.ErrorStatuses,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if f.config.Probability == 0 {
+		f.config.Probability = 1
+	}
+	if len(f.statuses) == 0 {
+		f.statuses = defaultFaultStatuses
+	}
+	for _, status := range f.statuses {
+		if status < 400 || status > 599 {
+			return nil, fmt.Errorf("invalid fault error status %d", status)
+		}
+	}
+	for _, path := range config.Paths {
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		f.paths = append(f.paths, globRegexp(path, true))
+	}
+	if !config.Paused {
+		f.enabled = 1
+	}
+
+	return f, nil
+}
+
+// plan decides which faults to inject into an HTTP request
+func (f *faultInjector) plan(requestURI string) (faultPlan, bool) {
+	if !f.isEnabled() || !f.matchesPath(requestURI) || !f.roll(f.config.Probability) {
+		return faultPlan{}, false
+	}
+
+	plan := faultPlan{
+		delay:    f.latency(),
+		throttle: f.config.Bandwidth > 0,
+	}
+	switch {
+	case f.roll(f.config.DropRate):
+		plan.drop = true
+		atomic.AddInt64(&f.dropped, 1)
+	case f.roll(f.config.ErrorRate):
+		f.mutex.Lock()
+		plan.status = f.statuses[f.rand.Intn(len(f.statuses))]
+		f.mutex.Unlock()
+		atomic.AddInt64(&f.errors, 1)
+	}
+	if plan.delay > 0 || plan.throttle {
+		atomic.AddInt64(&f.delayed, 1)
+	}
+	return plan, true
+}
+
+// affectsConnection decides whether faults are injected into a TCP connection
+func (f *faultInjector) affectsConnection() bool {
+	return f.isEnabled() && f.roll(f.config.Probability)
+}
+
+// chunk decides the faults of a chunk of n bytes sent over an affected TCP
+// connection: whether to drop the connection, and otherwise how long to wait
+func (f *faultInjector) chunk(n int) (time.Duration, bool) {
+	if !f.isEnabled() {
+		return 0, false
+	}
+	if f.roll(f.config.DropRate) {
+		atomic.AddInt64(&f.dropped, 1)
+		return 0, true
+	}
+	delay := f.latency() + f.transferTime(n)
+	if delay > 0 {
+		atomic.AddInt64(&f.delayed, 1)
+	}
+	return delay, false
+}
+
+// transferTime returns how long n bytes take at the throttled bandwidth
+func (f *faultInjector) transferTime(n int) time.Duration {
+	if f.config.Bandwidth <= 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / float64(f.config.Bandwidth) * float64(time.Second))
+}
+
+// setEnabled turns fault injection on or off
+func (f *faultInjector) setEnabled(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+	atomic.StoreInt32(&f.enabled, value)
+}
+
+// status returns the runtime state of the injector
+func (f *faultInjector) status() model.FaultStatus {
+	return model.FaultStatus{
+		Enabled: f.isEnabled(),
+		Config:  f.config,
+		Delayed: atomic.LoadInt64(&f.delayed),
+		Errors:  atomic.LoadInt64(&f.errors),
+		Dropped: atomic.LoadInt64(&f.dropped),
+	}
+}
+
+// isEnabled reports whether faults are injected
+func (f *faultInjector) isEnabled() bool {
+	return f != nil && atomic.LoadInt32(&f.enabled) == 1
+}
+
+// matchesPath reports whether a request URI matches the path filters
+func (f *faultInjector) matchesPath(requestURI string) bool {
+	if len(f.paths) == 0 {
+		return true
+	}
+	path := requestURI
+	if idx := strings.IndexAny(path, "?#"); idx >= 0 {
+		path = path[:idx]
+	}
+	for _, pattern := range f.paths {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// latency returns the configured latency plus a random jitter
+func (f *faultInjector) latency() time.Duration {
+	delay := f.config.Latency
+	if f.config.Jitter > 0 {
+		f.mutex.Lock()
+		delay += time.Duration(f.rand.Int63n(int64(f.config.Jitter) + 1))
+		f.mutex.Unlock()
+	}
+	return delay
+}
+
+// roll returns true with probability p
+func (f *faultInjector) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	if p >= 1 {
+		return true
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.rand.Float64() < p
+}
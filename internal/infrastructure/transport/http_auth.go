@@ -0,0 +1,108 @@
+package transport
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authRealm is the realm announced in WWW-Authenticate challenges
+const authRealm = "haxorport"
+
+// authorizeRequest verifies the credentials of a request against the
+// tunnel's basic or header authentication. It returns the headers of the
+// 401 challenge to send when the request is not authorized.
+func authorizeRequest(auth *model.TunnelAuth, header http.Header) (bool, http.Header) {
+	if auth == nil {
+		return true, nil
+	}
+
+	switch auth.Type {
+	case model.AuthTypeBasic:
+		username, password, ok := parseBasicAuth(header.Get("Authorization"))
+		if ok && checkBasicCredentials(auth, username, password) {
+			return true, nil
+		}
+		return false, http.Header{"Www-Authenticate": {`Basic realm="` + authRealm + `", charset="UTF-8"`}}
+	case model.AuthTypeHeader:
+		if auth.HeaderName != "" && auth.HeaderValue != "" && secureCompare(header.Get(auth.HeaderName), auth.HeaderValue) {
+			return true, nil
+		}
+		return false, http.Header{"Www-Authenticate": {`Header realm="` + authRealm + `", header="` + auth.HeaderName + `"`}}
+	default:
+		return true, nil
+	}
+}
+
+// validateAuth checks the basic or header authentication of an HTTP tunnel,
+// so that a setting that can never match, or that matches any request, is
+// rejected when the tunnel is created
+func validateAuth(auth *model.TunnelAuth) error {
+	switch auth.Type {
+	case model.AuthTypeBasic:
+		if auth.Username == "" {
+			return fmt.Errorf("basic auth requires a username")
+		}
+		if auth.PasswordHash != "" {
+			if _, err := bcrypt.Cost([]byte(auth.PasswordHash)); err != nil {
+				return fmt.Errorf("invalid basic auth password hash: %v", err)
+			}
+		} else if auth.Password == "" {
+			return fmt.Errorf("basic auth requires a password or password hash")
+		}
+	case model.AuthTypeHeader:
+		if auth.HeaderName == "" || auth.HeaderValue == "" {
+			return fmt.Errorf("header auth requires a header name and value")
+		}
+	}
+	return nil
+}
+
+// serverAuth returns the authentication to send to the server when a tunnel
+// is registered. The client enforces every auth type of HTTP tunnels itself,
+// so their settings and secrets never leave the machine.
+func serverAuth(tunnelType model.TunnelType, auth *model.TunnelAuth) *model.TunnelAuth {
+	if auth == nil || tunnelType == model.TunnelTypeHTTP || auth.Type == model.AuthTypeOIDC || auth.Type == model.AuthTypeJWT {
+		return nil
+	}
+	return auth
+}
+
+// checkBasicCredentials compares basic auth credentials in constant time.
+// A bcrypt PasswordHash takes precedence over a plaintext Password.
+func checkBasicCredentials(auth *model.TunnelAuth, username string, password string) bool {
+	usernameOK := secureCompare(username, auth.Username)
+
+	var passwordOK bool
+	if auth.PasswordHash != "" {
+		passwordOK = bcrypt.CompareHashAndPassword([]byte(auth.PasswordHash), []byte(password)) == nil
+	} else {
+		passwordOK = auth.Password != "" && secureCompare(password, auth.Password)
+	}
+
+	return usernameOK && passwordOK
+}
+
+// secureCompare compares two strings in constant time
+func secureCompare(a string, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// parseBasicAuth parses an "Authorization: Basic ..." header value
+func parseBasicAuth(value string) (string, string, bool) {
+	const prefix = "Basic "
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value[len(prefix):]))
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	return username, password, ok
+}
@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidateAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		auth    model.TunnelAuth
+		wantErr bool
+	}{
+		{"basic with password", model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", Password: "secret"}, false},
+		{"basic with hash", model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", PasswordHash: string(hash)}, false},
+		{"basic without username", model.TunnelAuth{Type: model.AuthTypeBasic, Password: "secret"}, true},
+		{"basic without password", model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user"}, true},
+		{"basic with invalid hash", model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", PasswordHash: "not-bcrypt"}, true},
+		{"header", model.TunnelAuth{Type: model.AuthTypeHeader, HeaderName: "X-API-Key", HeaderValue: "key"}, false},
+		{"header without value", model.TunnelAuth{Type: model.AuthTypeHeader, HeaderName: "X-API-Key"}, true},
+		{"header without name", model.TunnelAuth{Type: model.AuthTypeHeader, HeaderValue: "key"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth
+			if err := validateAuth(&auth); (err != nil) != tt.wantErr {
+				t.Errorf("validateAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRequest(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	basic := func(username, password string) http.Header {
+		return http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))}}
+	}
+
+	tests := []struct {
+		name   string
+		auth   *model.TunnelAuth
+		header http.Header
+		want   bool
+	}{
+		{"no auth", nil, http.Header{}, true},
+		{"basic ok", &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", Password: "secret"}, basic("user", "secret"), true},
+		{"basic wrong password", &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", Password: "secret"}, basic("user", "nope"), false},
+		{"basic missing", &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", Password: "secret"}, http.Header{}, false},
+		{"basic hash ok", &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", PasswordHash: string(hash)}, basic("user", "secret"), true},
+		{"basic hash wrong", &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", PasswordHash: string(hash)}, basic("user", ""), false},
+		{"header ok", &model.TunnelAuth{Type: model.AuthTypeHeader, HeaderName: "X-API-Key", HeaderValue: "key"}, http.Header{"X-Api-Key": {"key"}}, true},
+		{"header wrong", &model.TunnelAuth{Type: model.AuthTypeHeader, HeaderName: "X-API-Key", HeaderValue: "key"}, http.Header{"X-Api-Key": {"other"}}, false},
+		{"header empty value never matches", &model.TunnelAuth{Type: model.AuthTypeHeader, HeaderName: "X-API-Key"}, http.Header{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, challenge := authorizeRequest(tt.auth, tt.header)
+			if ok != tt.want {
+				t.Errorf("authorizeRequest() = %v, want %v", ok, tt.want)
+			}
+			if !ok && challenge.Get("WWW-Authenticate") == "" {
+				t.Error("rejected request has no WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestServerAuth(t *testing.T) {
+	basic := &model.TunnelAuth{Type: model.AuthTypeBasic, Username: "user", PasswordHash: "$2a$10$hash"}
+	header := &model.TunnelAuth{Type: model.AuthTypeHeader, HeaderName: "X-API-Key", HeaderValue: "key"}
+
+	tests := []struct {
+		name       string
+		tunnelType model.TunnelType
+		auth       *model.TunnelAuth
+		want       *model.TunnelAuth
+	}{
+		{"http basic stays on the client", model.TunnelTypeHTTP, basic, nil},
+		{"http header stays on the client", model.TunnelTypeHTTP, header, nil},
+		{"http oidc stays on the client", model.TunnelTypeHTTP, &model.TunnelAuth{Type: model.AuthTypeOIDC}, nil},
+		{"tcp basic goes to the server", model.TunnelTypeTCP, basic, basic},
+		{"tcp jwt stays on the client", model.TunnelTypeTCP, &model.TunnelAuth{Type: model.AuthTypeJWT}, nil},
+		{"no auth", model.TunnelTypeTCP, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serverAuth(tt.tunnelType, tt.auth); got != tt.want {
+				t.Errorf("serverAuth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	defaultHealthCheckTimeout  = 2 * time.Second
+)
+
+// upstream is a single local service behind a load balanced tunnel
+type upstream struct {
+	baseURL string
+	healthy int32
+	active  int64
+}
+
+// isHealthy reports whether the upstream is in rotation
+func (u *upstream) isHealthy() bool {
+	return atomic.LoadInt32(&u.healthy) == 1
+}
+
+// setHealthy updates the health state and reports whether it changed
+func (u *upstream) setHealthy(healthy bool) bool {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	return atomic.SwapInt32(&u.healthy, v) != v
+}
+
+// upstreamPool spreads requests across several upstreams
+type upstreamPool struct {
+	upstreams  []*upstream
+	strategy   model.LoadBalanceStrategy
+	hashHeader string
+	next       uint64
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+}
+
+// newUpstreamPool creates a pool for the given addresses, using scheme for
+// addresses without one. All upstreams start healthy.
+func newUpstreamPool(addrs []string, scheme string, strategy model.LoadBalanceStrategy, hashHeader string) (*upstreamPool, error) {
+	switch strategy {
+	case "":
+		strategy = model.LoadBalanceRoundRobin
+	case model.LoadBalanceRoundRobin, model.LoadBalanceLeastConn:
+	case model.LoadBalanceHash:
+		if hashHeader == "" {
+			return nil, fmt.Errorf("load balance strategy %s requires a hash header", strategy)
+		}
+	default:
+		return nil, fmt.Errorf("invalid load balance strategy: %s", strategy)
+	}
+
+	pool := &upstreamPool{
+		strategy:   strategy,
+		hashHeader: hashHeader,
+		stopCh:     make(chan struct{}),
+	}
+	for _, addr := range addrs {
+		pool.upstreams = append(pool.upstreams, &upstream{baseURL: upstreamURL(addr, scheme), healthy: 1})
+	}
+
+	return pool, nil
+}
+
+// pick selects an upstream for a request and marks it active.
+// The returned function must be called once the request is done.
+func (p *upstreamPool) pick(header http.Header) (*upstream, func(), error) {
+	var selected *upstream
+
+	switch p.strategy {
+	case model.LoadBalanceLeastConn:
+		for _, u := range p.upstreams {
+			if !u.isHealthy() {
+				continue
+			}
+			if selected == nil || atomic.LoadInt64(&u.active) < atomic.LoadInt64(&selected.active) {
+				selected = u
+			}
+		}
+	case model.LoadBalanceHash:
+		if value := header.Get(p.hashHeader); value != "" {
+			h := fnv.New32a()
+			h.Write([]byte(value))
+			selected = p.firstHealthy(int(h.Sum32() % uint32(len(p.upstreams))))
+			break
+		}
+		selected = p.firstHealthy(int(atomic.AddUint64(&p.next, 1) % uint64(len(p.upstreams))))
+	default:
+		selected = p.firstHealthy(int(atomic.AddUint64(&p.next, 1) % uint64(len(p.upstreams))))
+	}
+
+	if selected == nil {
+		return nil, nil, fmt.Errorf("no healthy upstream available")
+	}
+
+	atomic.AddInt64(&selected.active, 1)
+	return selected, func() { atomic.AddInt64(&selected.active, -1) }, nil
+}
+
+// firstHealthy returns the first healthy upstream starting at index start
+func (p *upstreamPool) firstHealthy(start int) *upstream {
+	for i := 0; i < len(p.upstreams); i++ {
+		u := p.upstreams[(start+i)%len(p.upstreams)]
+		if u.isHealthy() {
+			return u
+		}
+	}
+	return nil
+}
+
+// startHealthChecks probes every upstream periodically until the pool is closed.
+// HTTP checks are sent with the tunnel's transport so upstream TLS settings apply.
+func (p *upstreamPool) startHealthChecks(config *model.HealthCheckConfig, transport http.RoundTripper, logger port.Logger) {
+	if config == nil {
+		return
+	}
+
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthCheckTimeout
+	}
+	client := &http.Client{Transport: transport, Timeout: timeout}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			for _, u := range p.upstreams {
+				err := checkUpstream(client, u.baseURL, config.Path, timeout)
+				if u.setHealthy(err == nil) {
+					if err != nil {
+						logger.Warn("Upstream %s is unhealthy, removed from rotation: %v", u.baseURL, err)
+					} else {
+						logger.Info("Upstream %s is healthy again", u.baseURL)
+					}
+				}
+			}
+
+			select {
+			case <-ticker.C:
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// close stops the health checks of the pool
+func (p *upstreamPool) close() {
+	p.stopOnce.Do(func() { close(p.stopCh) })
+}
+
+// checkUpstream probes a single upstream with an HTTP GET on path,
+// or with a TCP connect when path is empty
+func checkUpstream(client *http.Client, baseURL string, path string, timeout time.Duration) error {
+	if path == "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return err
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		conn, err := dialUpstream(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	resp, err := client.Get(baseURL + ensureLeadingSlash(path))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
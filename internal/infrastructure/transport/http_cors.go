@@ -0,0 +1,200 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// defaultCORSMaxAge is how long browsers cache preflight responses by default
+const defaultCORSMaxAge = 10 * time.Minute
+
+// defaultCORSMethods are the methods allowed when none are configured
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// corsPolicy answers preflight requests and adds CORS headers to the
+// responses of a tunnel
+type corsPolicy struct {
+	anyOrigin     bool
+	origins       map[string]bool
+	patterns      []*regexp.Regexp
+	methods       []string
+	headers       []string
+	exposeHeaders []string
+	credentials   bool
+	maxAge        time.Duration
+}
+
+// newCORSPolicy creates the CORS policy of a tunnel. It returns nil when the
+// tunnel has no CORS policy; a nil policy adds no headers.
+func newCORSPolicy(config *model.CORSConfig) (*corsPolicy, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if len(config.AllowOrigins) == 0 {
+		return nil, fmt.Errorf("CORS requires at least one allowed origin")
+	}
+	if config.MaxAge < 0 {
+		return nil, fmt.Errorf("CORS max age must not be negative")
+	}
+
+	p := &corsPolicy{
+		origins:       make(map[string]bool),
+		methods:       upperAll(config.AllowMethods),
+		headers:       config.AllowHeaders,
+		exposeHeaders: config.ExposeHeaders,
+		credentials:   config.AllowCredentials,
+		maxAge:        config.MaxAge,
+	}
+	if len(p.methods) == 0 {
+		p.methods = defaultCORSMethods
+	}
+	if p.maxAge == 0 {
+		p.maxAge = defaultCORSMaxAge
+	}
+
+	for _, origin := range config.AllowOrigins {
+		origin = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			p.patterns = append(p.patterns, originPattern(origin))
+		default:
+			p.origins[origin] = true
+		}
+	}
+	if p.anyOrigin && p.credentials {
+		return nil, fmt.Errorf("CORS credentials cannot be allowed for any origin; list the allowed origins instead")
+	}
+
+	return p, nil
+}
+
+// isPreflight reports whether a request is a CORS preflight request
+func (p *corsPolicy) isPreflight(request *model.HTTPRequest) bool {
+	return p != nil && request.Method == http.MethodOptions &&
+		request.Headers.Get("Origin") != "" && request.Headers.Get("Access-Control-Request-Method") != ""
+}
+
+// preflight returns the headers of the response to a preflight request, or
+// false when the origin or the requested method is not allowed
+func (p *corsPolicy) preflight(header http.Header) (http.Header, bool) {
+	response := http.Header{}
+	response.Add("Vary", "Origin")
+	response.Add("Vary", "Access-Control-Request-Method")
+	response.Add("Vary", "Access-Control-Request-Headers")
+
+	origin, ok := p.allowOrigin(header.Get("Origin"))
+	if !ok || !containsFold(p.methods, header.Get("Access-Control-Request-Method")) {
+		return response, false
+	}
+
+	response.Set("Access-Control-Allow-Origin", origin)
+	response.Set("Access-Control-Allow-Methods", strings.Join(p.methods, ", "))
+	if len(p.headers) > 0 {
+		response.Set("Access-Control-Allow-Headers", strings.Join(p.headers, ", "))
+	} else if requested := header.Get("Access-Control-Request-Headers"); requested != "" {
+		response.Set("Access-Control-Allow-Headers", requested)
+	}
+	if p.credentials {
+		response.Set("Access-Control-Allow-Credentials", "true")
+	}
+	response.Set("Access-Control-Max-Age", strconv.Itoa(int(p.maxAge.Seconds())))
+
+	return response, true
+}
+
+// decorate replaces the CORS headers of a response with the tunnel's policy
+// for the origin of the request
+func (p *corsPolicy) decorate(response http.Header, origin string) {
+	if p == nil {
+		return
+	}
+
+	for name := range response {
+		if strings.HasPrefix(name, "Access-Control-") {
+			response.Del(name)
+		}
+	}
+
+	allowed, ok := p.allowOrigin(origin)
+	if !p.anyOrigin || p.credentials {
+		response.Add("Vary", "Origin")
+	}
+	if !ok {
+		return
+	}
+
+	response.Set("Access-Control-Allow-Origin", allowed)
+	if p.credentials {
+		response.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(p.exposeHeaders) > 0 {
+		response.Set("Access-Control-Expose-Headers", strings.Join(p.exposeHeaders, ", "))
+	}
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin for an
+// origin, or false when the origin is not allowed
+func (p *corsPolicy) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+	if p.anyOrigin {
+		return "*", true
+	}
+
+	normalized := strings.ToLower(origin)
+	if p.origins[normalized] {
+		return origin, true
+	}
+	for _, pattern := range p.patterns {
+		if pattern.MatchString(normalized) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// originPattern compiles an origin with wildcards such as
+// "https://*.example.com"; "*" does not match "/" or ":" so that it stays
+// within the host
+func originPattern(origin string) *regexp.Regexp {
+	parts := strings.Split(origin, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "[^/:]*") + "$")
+}
+
+// upperAll returns the values in upper case
+func upperAll(values []string) []string {
+	upper := make([]string, 0, len(values))
+	for _, value := range values {
+		upper = append(upper, strings.ToUpper(strings.TrimSpace(value)))
+	}
+	return upper
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
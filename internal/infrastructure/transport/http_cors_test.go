@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+func TestOriginPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"https://*.example.com", "https://app.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "http://app.example.com", false},
+		{"https://*.example.com", "https://app.example.com:8443", false},
+		{"https://*.example.com", "https://app.example.com.evil.com", false},
+		{"https://*.example.com", "https://evil.com/.example.com", false},
+		{"https://*.vercel.app", "https://my-app-git-main.vercel.app", true},
+		{"http://localhost:*", "http://localhost:5173", true},
+		{"http://localhost:*", "http://localhost.evil.com:5173", false},
+		{"https://app.example.com", "https://appxexample.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := originPattern(tt.pattern).MatchString(tt.origin); got != tt.want {
+			t.Errorf("originPattern(%q) matches %q = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCORSAllowOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		origin  string
+		want    string
+		allowed bool
+	}{
+		{"exact", []string{"https://app.example.com"}, "https://app.example.com", "https://app.example.com", true},
+		{"case and trailing slash in config", []string{"HTTPS://App.Example.com/"}, "https://app.example.com", "https://app.example.com", true},
+		{"other origin", []string{"https://app.example.com"}, "https://evil.example.com", "", false},
+		{"wildcard", []string{"https://*.example.com"}, "https://preview.example.com", "https://preview.example.com", true},
+		{"any origin", []string{"*"}, "https://anything.test", "*", true},
+		{"no origin", []string{"*"}, "", "", false},
+		{"null origin", []string{"https://app.example.com"}, "null", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newCORSPolicy(&model.CORSConfig{AllowOrigins: tt.origins})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := p.allowOrigin(tt.origin)
+			if got != tt.want || ok != tt.allowed {
+				t.Errorf("allowOrigin(%q) = %q, %v; want %q, %v", tt.origin, got, ok, tt.want, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNewCORSPolicyRejectsInvalidConfig(t *testing.T) {
+	for _, config := range []*model.CORSConfig{
+		{},
+		{AllowOrigins: []string{"*"}, AllowCredentials: true},
+		{AllowOrigins: []string{"https://app.example.com"}, MaxAge: -1},
+	} {
+		if _, err := newCORSPolicy(config); err == nil {
+			t.Errorf("newCORSPolicy(%+v) succeeded, want error", config)
+		}
+	}
+}
+
+func TestCORSPreflightAndDecorate(t *testing.T) {
+	p, err := newCORSPolicy(&model.CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com"},
+		AllowMethods:     []string{"get", "post"},
+		AllowCredentials: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := http.Header{
+		"Origin":                         {"https://app.example.com"},
+		"Access-Control-Request-Method":  {"POST"},
+		"Access-Control-Request-Headers": {"Content-Type"},
+	}
+	response, ok := p.preflight(request)
+	if !ok {
+		t.Fatal("preflight rejected an allowed origin and method")
+	}
+	if got := response.Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Access-Control-Allow-Methods = %q", got)
+	}
+	if got := response.Get("Access-Control-Allow-Headers"); got != "Content-Type" {
+		t.Errorf("Access-Control-Allow-Headers = %q", got)
+	}
+
+	request.Set("Access-Control-Request-Method", "DELETE")
+	if _, ok := p.preflight(request); ok {
+		t.Error("preflight allowed a method outside the policy")
+	}
+
+	// Upstream CORS headers are replaced by the tunnel's policy
+	header := http.Header{"Access-Control-Allow-Origin": {"*"}, "Access-Control-Allow-Methods": {"PUT"}}
+	p.decorate(header, "https://evil.example.com")
+	if len(header.Values("Access-Control-Allow-Origin")) != 0 || len(header.Values("Access-Control-Allow-Methods")) != 0 {
+		t.Errorf("decorate kept upstream CORS headers for a rejected origin: %v", header)
+	}
+
+	header = http.Header{}
+	p.decorate(header, "https://app.example.com")
+	if header.Get("Access-Control-Allow-Origin") != "https://app.example.com" || header.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("decorate headers = %v", header)
+	}
+}
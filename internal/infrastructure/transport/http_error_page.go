@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultErrorPage is the branded page shown when the client answers a
+// request itself, for example when the local service is down
+var defaultErrorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.StatusCode}} {{.Status}} · {{.Tunnel}}</title>
+<style>
+body{margin:0;min-height:100vh;display:flex;align-items:center;justify-content:center;background:#0f172a;color:#e2e8f0;font-family:-apple-system,BlinkMacSystemFont,"Segoe UI",Roboto,sans-serif}
+main{max-width:32rem;padding:2rem;text-align:center}
+h1{margin:0;font-size:4rem;color:#38bdf8}
+h2{margin:.5rem 0 1rem;font-weight:500}
+p{line-height:1.5;color:#94a3b8}
+code{color:#e2e8f0}
+footer{margin-top:2rem;font-size:.8rem;color:#475569}
+</style>
+</head>
+<body>
+<main>
+<h1>{{.StatusCode}}</h1>
+<h2>{{.Status}}</h2>
+<p>{{.Message}}</p>
+{{if .Retry}}<p>{{if .RetryAfter}}Please try again in {{.RetryAfter}} seconds.{{else}}Please try again in a few moments.{{end}}</p>{{end}}
+<footer>Tunnel <code>{{.Tunnel}}</code>{{if .RequestID}} · Request <code>{{.RequestID}}</code>{{end}} · Haxorport</footer>
+</main>
+</body>
+</html>
+`))
+
+// errorMessages are the visitor facing explanations of the statuses the
+// client answers itself. Internal error details are only logged.
+var errorMessages = map[int]string{
+	http.StatusUnauthorized:          "Authentication is required to access this tunnel.",
+	http.StatusForbidden:             "You are not allowed to access this tunnel.",
+	http.StatusRequestEntityTooLarge: "The request body is larger than this tunnel accepts.",
+	http.StatusTooManyRequests:       "Too many requests were sent to this tunnel.",
+	http.StatusServiceUnavailable:    "The tunnel is temporarily unable to handle this request.",
+	http.StatusInternalServerError:   "The tunnel client could not handle this request.",
+	http.StatusBadGateway:            "The local service behind this tunnel is not reachable or sent an invalid response. It may be starting up or stopped.",
+	http.StatusGatewayTimeout:        "The local service behind this tunnel took too long to respond.",
+}
+
+// errorPageData is passed to error page templates and returned as JSON
+type errorPageData struct {
+	StatusCode int    `json:"status"`
+	Status     string `json:"error"`
+	Message    string `json:"message"`
+	Tunnel     string `json:"tunnel"`
+	RequestID  string `json:"request_id,omitempty"`
+	RetryAfter string `json:"retry_after,omitempty"`
+	Retry      bool   `json:"-"`
+}
+
+// errorPages renders error responses, using the tunnel's custom templates
+// when there are any
+type errorPages struct {
+	templates map[string]*template.Template
+}
+
+// newErrorPages loads custom error page templates keyed by status code
+// ("502") or status class ("5xx")
+func newErrorPages(paths map[string]string) (*errorPages, error) {
+	pages := &errorPages{templates: make(map[string]*template.Template)}
+	for key, path := range paths {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !isErrorPageKey(key) {
+			return nil, fmt.Errorf("invalid error page key %q, expected a status code or class such as 502 or 5xx", key)
+		}
+		tmpl, err := template.ParseFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load error page %s: %v", path, err)
+		}
+		pages.templates[key] = tmpl
+	}
+	return pages, nil
+}
+
+// render returns the body and content type of an error response. JSON is
+// returned to clients that accept it but not HTML.
+func (p *errorPages) render(data errorPageData, accept string) ([]byte, string) {
+	if strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html") {
+		body, _ := json.Marshal(data)
+		return append(body, '\n'), "application/json; charset=utf-8"
+	}
+
+	code := strconv.Itoa(data.StatusCode)
+	tmpl := defaultErrorPage
+	if custom, ok := p.templates[code]; ok {
+		tmpl = custom
+	} else if custom, ok := p.templates[code[:1]+"xx"]; ok {
+		tmpl = custom
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		buf.Reset()
+		defaultErrorPage.Execute(&buf, data)
+	}
+	return buf.Bytes(), "text/html; charset=utf-8"
+}
+
+// newErrorPageData describes an error response of a tunnel
+func newErrorPageData(statusCode int, tunnel string, requestID string, retryAfter string) errorPageData {
+	message, ok := errorMessages[statusCode]
+	if !ok {
+		message = http.StatusText(statusCode) + "."
+	}
+	return errorPageData{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Message:    message,
+		Tunnel:     tunnel,
+		RequestID:  requestID,
+		RetryAfter: retryAfter,
+		Retry:      statusCode == http.StatusTooManyRequests || statusCode >= 500,
+	}
+}
+
+// upstreamErrorStatus returns 504 Gateway Timeout for upstream errors caused
+// by a timeout and 502 Bad Gateway for any other upstream error
+func upstreamErrorStatus(err error) int {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// isErrorPageKey reports whether key is an error status code or class
+func isErrorPageKey(key string) bool {
+	if len(key) != 3 || (key[0] != '4' && key[0] != '5') {
+		return false
+	}
+	if key[1:] == "xx" {
+		return true
+	}
+	_, err := strconv.Atoi(key)
+	return err == nil
+}
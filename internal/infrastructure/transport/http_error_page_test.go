@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsErrorPageKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"502", true},
+		{"404", true},
+		{"5xx", true},
+		{"4xx", true},
+		{"200", false},
+		{"3xx", false},
+		{"6xx", false},
+		{"50", false},
+		{"5021", false},
+		{"5x0", false},
+		{"4+1", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := isErrorPageKey(tt.key); got != tt.want {
+				t.Errorf("isErrorPageKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorPagesRender(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	pages, err := newErrorPages(map[string]string{
+		"502": write("502.html", "down {{.Tunnel}}"),
+		"5XX": write("5xx.html", "server error {{.StatusCode}}"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name            string
+		status          int
+		accept          string
+		wantBody        string
+		wantContentType string
+	}{
+		{"exact status", http.StatusBadGateway, "text/html", "down web", "text/html; charset=utf-8"},
+		{"status class", http.StatusGatewayTimeout, "text/html", "server error 504", "text/html; charset=utf-8"},
+		{"default page", http.StatusForbidden, "", "You are not allowed to access this tunnel.", "text/html; charset=utf-8"},
+		{"json", http.StatusBadGateway, "application/json", `"status":502`, "application/json; charset=utf-8"},
+		{"html preferred over json", http.StatusBadGateway, "text/html, application/json", "down web", "text/html; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := pages.render(newErrorPageData(tt.status, "web", "req-1", ""), tt.accept)
+			if contentType != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.wantContentType)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewErrorPagesRejectsInvalidKeys(t *testing.T) {
+	if _, err := newErrorPages(map[string]string{"200": "ok.html"}); err == nil {
+		t.Error("newErrorPages accepted key 200")
+	}
+}
+
+func TestUpstreamErrorStatus(t *testing.T) {
+	if got := upstreamErrorStatus(context.DeadlineExceeded); got != http.StatusGatewayTimeout {
+		t.Errorf("upstreamErrorStatus(deadline) = %d, want %d", got, http.StatusGatewayTimeout)
+	}
+	if got := upstreamErrorStatus(errors.New("connection refused")); got != http.StatusBadGateway {
+		t.Errorf("upstreamErrorStatus(refused) = %d, want %d", got, http.StatusBadGateway)
+	}
+}
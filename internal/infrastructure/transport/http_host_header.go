@@ -0,0 +1,57 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// upstreamHostHeader returns the Host header to send to the local service
+// for the given mode: the upstream host for "rewrite" (or empty), the public
+// host for "preserve", and the mode itself for any other value.
+func upstreamHostHeader(mode string, publicHost string, upstreamHost string) string {
+	switch mode {
+	case "", model.HostHeaderRewrite:
+		return upstreamHost
+	case model.HostHeaderPreserve:
+		if publicHost == "" {
+			return upstreamHost
+		}
+		return publicHost
+	default:
+		return mode
+	}
+}
+
+// applyHostHeader sets the Host header of the upstream request according to
+// the tunnel's host header mode. When the host is changed, Origin and Referer
+// headers pointing at the public host are rewritten as well so that CSRF
+// checks in the local service see a consistent origin.
+func applyHostHeader(req *http.Request, mode string, publicHost string) {
+	host := upstreamHostHeader(mode, publicHost, upstreamDisplayHost(req.URL.Host))
+	req.Host = host
+
+	if host == publicHost || publicHost == "" {
+		return
+	}
+
+	if origin := req.Header.Get("Origin"); origin != "" {
+		req.Header.Set("Origin", rewriteURLHost(origin, publicHost, host, req.URL.Scheme))
+	}
+	if referer := req.Header.Get("Referer"); referer != "" {
+		req.Header.Set("Referer", rewriteURLHost(referer, publicHost, host, req.URL.Scheme))
+	}
+}
+
+// rewriteURLHost replaces the scheme and host of rawURL when its host equals
+// from. URLs pointing at other hosts are returned unchanged.
+func rewriteURLHost(rawURL string, from string, to string, scheme string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != from {
+		return rawURL
+	}
+	u.Scheme = scheme
+	u.Host = to
+	return u.String()
+}
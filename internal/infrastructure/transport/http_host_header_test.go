@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUpstreamHostHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		publicHost string
+		want       string
+	}{
+		{"default rewrites to upstream", "", "app.haxorport.online", "localhost:5173"},
+		{"rewrite", "rewrite", "app.haxorport.online", "localhost:5173"},
+		{"preserve", "preserve", "app.haxorport.online", "app.haxorport.online"},
+		{"preserve without public host", "preserve", "", "localhost:5173"},
+		{"custom value", "myapp.local", "app.haxorport.online", "myapp.local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upstreamHostHeader(tt.mode, tt.publicHost, "localhost:5173"); got != tt.want {
+				t.Errorf("upstreamHostHeader(%q) = %q, want %q", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHostHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		wantHost    string
+		wantOrigin  string
+		wantReferer string
+	}{
+		{"default", "", "localhost:5173", "http://localhost:5173", "http://localhost:5173/page"},
+		{"preserve", "preserve", "app.haxorport.online", "https://app.haxorport.online", "https://app.haxorport.online/page"},
+		{"custom", "myapp.local", "myapp.local", "http://myapp.local", "http://myapp.local/page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "http://localhost:5173/form", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Origin", "https://app.haxorport.online")
+			req.Header.Set("Referer", "https://app.haxorport.online/page")
+
+			applyHostHeader(req, tt.mode, "app.haxorport.online")
+
+			if req.Host != tt.wantHost {
+				t.Errorf("Host = %q, want %q", req.Host, tt.wantHost)
+			}
+			if got := req.Header.Get("Origin"); got != tt.wantOrigin {
+				t.Errorf("Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := req.Header.Get("Referer"); got != tt.wantReferer {
+				t.Errorf("Referer = %q, want %q", got, tt.wantReferer)
+			}
+		})
+	}
+}
+
+func TestRewriteURLHostKeepsOtherHosts(t *testing.T) {
+	got := rewriteURLHost("https://other.example.com/x", "app.haxorport.online", "localhost:3000", "http")
+	if got != "https://other.example.com/x" {
+		t.Errorf("rewriteURLHost = %q, want URL unchanged", got)
+	}
+}
@@ -0,0 +1,462 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	// jwksRefreshInterval is how long keys fetched from a JWKS URL are used
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval limits refetches triggered by unknown key IDs
+	jwksMinRefreshInterval = time.Minute
+)
+
+// errNoBearerToken is returned when a request carries no bearer token
+var errNoBearerToken = errors.New("no bearer token")
+
+// jwtMethods lists the signing algorithms accepted for JWT auth. The key
+// type decides which of them can verify a given token.
+var jwtMethods = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// jwtKey is a verification key with its optional key ID
+type jwtKey struct {
+	kid string
+	key interface{}
+}
+
+// jwtVerifier validates bearer tokens for a tunnel with JWT auth
+type jwtVerifier struct {
+	auth    *model.TunnelAuth
+	keys    []jwtKey
+	jwksURL string
+	client  *http.Client
+
+	mutex     sync.Mutex
+	jwksKeys  []jwtKey
+	fetchedAt time.Time
+}
+
+// newJWTVerifier loads the static key, public key file or JWKS file of a
+// tunnel. Keys from a JWKS URL are fetched when the first token arrives.
+func newJWTVerifier(auth *model.TunnelAuth) (*jwtVerifier, error) {
+	v := &jwtVerifier{
+		auth:   auth,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if auth.JWTSecret != "" {
+		v.keys = append(v.keys, jwtKey{key: []byte(auth.JWTSecret)})
+	}
+
+	if auth.JWTPublicKey != "" {
+		key, err := loadPublicKey(auth.JWTPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, jwtKey{key: key})
+	}
+
+	switch {
+	case strings.HasPrefix(auth.JWKS, "http://") || strings.HasPrefix(auth.JWKS, "https://"):
+		v.jwksURL = auth.JWKS
+	case auth.JWKS != "":
+		data, err := os.ReadFile(auth.JWKS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %v", err)
+		}
+		keys, err := parseJWKS(data)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, keys...)
+	}
+
+	if len(v.keys) == 0 && v.jwksURL == "" {
+		return nil, fmt.Errorf("jwt auth requires a secret, a public key or a JWKS")
+	}
+
+	return v, nil
+}
+
+// verify validates the bearer token of a request: its signature, expiry,
+// issuer, audience and required claims. It returns the token's claims.
+func (v *jwtVerifier) verify(header http.Header) (jwt.MapClaims, error) {
+	tokenString, ok := bearerToken(header.Get("Authorization"))
+	if !ok {
+		return nil, errNoBearerToken
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(jwtMethods), jwt.WithJSONNumber())
+	if _, err := parser.ParseWithClaims(tokenString, claims, v.keyFor); err != nil {
+		return nil, err
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if v.auth.Issuer != "" && !claims.VerifyIssuer(v.auth.Issuer, true) {
+		return nil, fmt.Errorf("token issuer mismatch")
+	}
+	if v.auth.Audience != "" && !claims.VerifyAudience(v.auth.Audience, true) {
+		return nil, fmt.Errorf("token audience mismatch")
+	}
+	for name, want := range v.auth.RequiredClaims {
+		if !claimMatches(claimValue(claims, name), want) {
+			return nil, fmt.Errorf("token claim %s does not match", name)
+		}
+	}
+
+	return claims, nil
+}
+
+// keyFor returns the key to verify a token with, chosen by key ID and by the
+// key type the token's algorithm needs. Unknown key IDs trigger a JWKS refresh.
+func (v *jwtVerifier) keyFor(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	alg := token.Method.Alg()
+
+	if key := selectKey(v.keys, kid, alg); key != nil {
+		return key, nil
+	}
+	if v.jwksURL == "" {
+		return nil, fmt.Errorf("no key for algorithm %s", alg)
+	}
+
+	keys, err := v.remoteKeys(false)
+	if err != nil {
+		return nil, err
+	}
+	if key := selectKey(keys, kid, alg); key != nil {
+		return key, nil
+	}
+
+	keys, err = v.remoteKeys(true)
+	if err != nil {
+		return nil, err
+	}
+	if key := selectKey(keys, kid, alg); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key for key ID %q and algorithm %s", kid, alg)
+}
+
+// remoteKeys returns the keys of the JWKS URL, fetching them again when they
+// are stale or, rate limited, when force is set
+func (v *jwtVerifier) remoteKeys(force bool) ([]jwtKey, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	age := time.Since(v.fetchedAt)
+	if v.jwksKeys != nil && age < jwksRefreshInterval && (!force || age < jwksMinRefreshInterval) {
+		return v.jwksKeys, nil
+	}
+
+	resp, err := v.client.Get(v.jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v.jwksKeys = keys
+	v.fetchedAt = time.Now()
+	return keys, nil
+}
+
+// selectKey returns the first key matching the key ID, if any, that can
+// verify the algorithm
+func selectKey(keys []jwtKey, kid string, alg string) interface{} {
+	for _, k := range keys {
+		if kid != "" && k.kid != "" && k.kid != kid {
+			continue
+		}
+		if keyFitsAlgorithm(k.key, alg) {
+			return k.key
+		}
+	}
+	return nil
+}
+
+// keyFitsAlgorithm reports whether a key has the type an algorithm needs,
+// so that public keys are never used as HMAC secrets
+func keyFitsAlgorithm(key interface{}, alg string) bool {
+	switch {
+	case strings.HasPrefix(alg, "HS"):
+		_, ok := key.([]byte)
+		return ok
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case strings.HasPrefix(alg, "ES"):
+		_, ok := key.(*ecdsa.PublicKey)
+		return ok
+	case alg == "EdDSA":
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	default:
+		return false
+	}
+}
+
+// loadPublicKey reads a PEM encoded public key or certificate from a file
+func loadPublicKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read public key: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %v", err)
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %v", err)
+		}
+		return key, nil
+	}
+}
+
+// parseJWKS parses the signing keys of a JSON Web Key Set. Keys of
+// unsupported types are skipped.
+func parseJWKS(data []byte) ([]jwtKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	var keys []jwtKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		var key interface{}
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = rsaKeyFromJWK(k.N, k.E)
+		case "EC":
+			key, err = ecKeyFromJWK(k.Crv, k.X, k.Y)
+		case "OKP":
+			if k.Crv != "Ed25519" {
+				continue
+			}
+			var x []byte
+			x, err = base64.RawURLEncoding.DecodeString(k.X)
+			if err == nil && len(x) != ed25519.PublicKeySize {
+				err = fmt.Errorf("invalid Ed25519 key size")
+			}
+			key = ed25519.PublicKey(x)
+		case "oct":
+			key, err = base64.RawURLEncoding.DecodeString(k.K)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS key %q: %v", k.Kid, err)
+		}
+		keys = append(keys, jwtKey{kid: k.Kid, key: key})
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no usable keys")
+	}
+	return keys, nil
+}
+
+// rsaKeyFromJWK builds an RSA public key from JWK parameters
+func rsaKeyFromJWK(n string, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(eBytes)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(exponent.Int64())}, nil
+}
+
+// ecKeyFromJWK builds an ECDSA public key from JWK parameters
+func ecKeyFromJWK(crv string, x string, y string) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %s", crv)
+	}
+
+	xBytes, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil {
+		return nil, err
+	}
+	yBytes, err := base64.RawURLEncoding.DecodeString(y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xBytes), Y: new(big.Int).SetBytes(yBytes)}
+	if !curve.IsOnCurve(key.X, key.Y) {
+		return nil, fmt.Errorf("point is not on curve %s", crv)
+	}
+	return key, nil
+}
+
+// bearerToken parses an "Authorization: Bearer ..." header value
+func bearerToken(value string) (string, bool) {
+	const prefix = "Bearer "
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(value[len(prefix):])
+	return token, token != ""
+}
+
+// bearerChallenge returns the WWW-Authenticate header of a 401 response for
+// a request whose bearer token was missing or rejected
+func bearerChallenge(err error) http.Header {
+	challenge := `Bearer realm="` + authRealm + `"`
+	if err != errNoBearerToken {
+		challenge += `, error="invalid_token"`
+	}
+	return http.Header{"Www-Authenticate": {challenge}}
+}
+
+// claimValue returns a claim by name. Dotted names such as
+// "realm_access.roles" select nested claims.
+func claimValue(claims jwt.MapClaims, name string) interface{} {
+	var value interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(name, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+	return value
+}
+
+// claimMatches reports whether a claim is present and, when want is not
+// empty, equal to want or, for list claims, contains want
+func claimMatches(value interface{}, want string) bool {
+	if value == nil {
+		return false
+	}
+	if want == "" {
+		return true
+	}
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if claimString(item) == want {
+				return true
+			}
+		}
+		return false
+	}
+	return claimString(value) == want
+}
+
+// claimString formats a claim for comparisons and upstream headers.
+// Lists are joined with commas.
+func claimString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, claimString(item))
+		}
+		return strings.Join(items, ",")
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// setClaimHeaders replaces the configured claim headers of an upstream
+// request with the claims of a verified token
+func setClaimHeaders(header http.Header, claimHeaders map[string]string, claims jwt.MapClaims) {
+	for claim, name := range claimHeaders {
+		header.Del(name)
+		if value := claimValue(claims, claim); value != nil {
+			header.Set(name, claimString(value))
+		}
+	}
+}
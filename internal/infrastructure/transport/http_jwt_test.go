@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWTVerify(t *testing.T) {
+	v, err := newJWTVerifier(&model.TunnelAuth{
+		Type:           model.AuthTypeJWT,
+		JWTSecret:      "secret",
+		Issuer:         "https://issuer.example.com",
+		Audience:       "my-api",
+		RequiredClaims: map[string]string{"scope": "webhooks", "realm_access.roles": "admin", "tenant": ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":          "https://issuer.example.com",
+			"aud":          []string{"other", "my-api"},
+			"exp":          time.Now().Add(time.Hour).Unix(),
+			"sub":          "user-1",
+			"scope":        "webhooks",
+			"tenant":       "acme",
+			"realm_access": map[string]interface{}{"roles": []string{"user", "admin"}},
+		}
+	}
+	with := func(name string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr bool
+	}{
+		{"valid", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), valid()), false},
+		{"lowercase scheme", "bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), valid()), false},
+		{"no token", "", true},
+		{"wrong secret", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("other"), valid()), true},
+		{"none algorithm", "Bearer " + signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid()), true},
+		{"expired", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("exp", time.Now().Add(-time.Minute).Unix())), true},
+		{"no expiry", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("exp", nil)), true},
+		{"wrong issuer", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("iss", "https://evil.example.com")), true},
+		{"wrong audience", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("aud", "other")), true},
+		{"wrong claim", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("scope", "admin")), true},
+		{"missing nested role", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("realm_access", map[string]interface{}{"roles": []string{"user"}})), true},
+		{"missing presence claim", "Bearer " + signTestToken(t, jwt.SigningMethodHS256, []byte("secret"), with("tenant", nil)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Authorization", tt.header)
+			}
+			claims, err := v.verify(header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && claimString(claims["sub"]) != "user-1" {
+				t.Errorf("sub = %v, want user-1", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestClaimMatches(t *testing.T) {
+	var claims jwt.MapClaims
+	if err := json.Unmarshal([]byte(`{
+		"scope": "read",
+		"count": 3,
+		"admin": true,
+		"groups": ["dev", "ops"],
+		"realm_access": {"roles": ["admin"]}
+	}`), &claims); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		claim string
+		want  string
+		match bool
+	}{
+		{"string equal", "scope", "read", true},
+		{"string differs", "scope", "write", false},
+		{"number", "count", "3", true},
+		{"bool", "admin", "true", true},
+		{"list contains", "groups", "ops", true},
+		{"list lacks", "groups", "qa", false},
+		{"nested list", "realm_access.roles", "admin", true},
+		{"presence only", "groups", "", true},
+		{"missing", "missing", "", false},
+		{"missing nested", "scope.roles", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := claimMatches(claimValue(claims, tt.claim), tt.want); got != tt.match {
+				t.Errorf("claimMatches(%s, %q) = %v, want %v", tt.claim, tt.want, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestSetClaimHeaders(t *testing.T) {
+	claims := jwt.MapClaims{"sub": "user-1", "groups": []interface{}{"dev", "ops"}}
+	header := http.Header{"X-User-Id": {"spoofed"}, "X-Tenant": {"spoofed"}}
+
+	setClaimHeaders(header, map[string]string{"sub": "X-User-ID", "groups": "X-Groups", "tenant": "X-Tenant"}, claims)
+
+	if got := header.Get("X-User-ID"); got != "user-1" {
+		t.Errorf("X-User-ID = %q, want user-1", got)
+	}
+	if got := header.Get("X-Groups"); got != "dev,ops" {
+		t.Errorf("X-Groups = %q, want dev,ops", got)
+	}
+	if _, ok := header["X-Tenant"]; ok {
+		t.Errorf("X-Tenant sent by the visitor was not removed")
+	}
+}
@@ -0,0 +1,197 @@
+package transport
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// clientLimiterIdle is how long the limiter of an idle visitor address is kept
+	clientLimiterIdle = 10 * time.Minute
+	// inFlightRetryAfter is suggested to visitors rejected by the in-flight cap
+	inFlightRetryAfter = time.Second
+)
+
+// clientLimiter is the token bucket of a single visitor address
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// requestLimiter applies the rate limits and the in-flight cap of an HTTP tunnel
+type requestLimiter struct {
+	tunnel     *rate.Limiter
+	perIPRate  rate.Limit
+	perIPBurst int
+	inFlight   chan struct{}
+	// now returns the current time to take tokens at
+	now func() time.Time
+
+	mutex     sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// newRequestLimiter creates the limiter of a tunnel. It returns nil when the
+// tunnel has no rate limit; a nil limiter allows every request.
+func newRequestLimiter(config *model.RateLimitConfig) (*requestLimiter, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if config.Rate < 0 || config.PerIPRate < 0 || config.Burst < 0 || config.PerIPBurst < 0 || config.MaxInFlight < 0 {
+		return nil, fmt.Errorf("rate limits must not be negative")
+	}
+
+	l := &requestLimiter{
+		perIPRate:  rate.Limit(config.PerIPRate),
+		perIPBurst: burstFor(config.PerIPRate, config.PerIPBurst),
+		now:        time.Now,
+		clients:    make(map[string]*clientLimiter),
+		lastSweep:  time.Now(),
+	}
+	if config.Rate > 0 {
+		l.tunnel = rate.NewLimiter(rate.Limit(config.Rate), burstFor(config.Rate, config.Burst))
+	}
+	if config.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, config.MaxInFlight)
+	}
+
+	return l, nil
+}
+
+// acquire admits a request from a visitor address. When the request is over
+// a limit, it returns false and how long the visitor should wait. Otherwise
+// the returned function must be called once the request is done.
+func (l *requestLimiter) acquire(remoteAddr string) (func(), time.Duration, bool) {
+	if l == nil {
+		return func() {}, 0, true
+	}
+
+	now := l.now()
+	var reservations []*rate.Reservation
+	cancel := func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+
+	if l.perIPRate > 0 {
+		r := l.clientLimiter(remoteIPKey(remoteAddr), now).ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if delay := r.DelayFrom(now); delay > 0 {
+			cancel()
+			return nil, delay, false
+		}
+	}
+
+	if l.tunnel != nil {
+		r := l.tunnel.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if delay := r.DelayFrom(now); delay > 0 {
+			cancel()
+			return nil, delay, false
+		}
+	}
+
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			return func() { <-l.inFlight }, 0, true
+		default:
+			cancel()
+			return nil, inFlightRetryAfter, false
+		}
+	}
+
+	return func() {}, 0, true
+}
+
+// clientLimiter returns the token bucket of a visitor address, dropping the
+// buckets of addresses that have been idle for a while
+func (l *requestLimiter) clientLimiter(key string, now time.Time) *rate.Limiter {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.lastSweep) > clientLimiterIdle {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > clientLimiterIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.perIPRate, l.perIPBurst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// burstFor returns the configured burst, or the rate rounded up (at least 1)
+func burstFor(r float64, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+	if r < 1 {
+		return 1
+	}
+	return int(math.Ceil(r))
+}
+
+// remoteIPKey returns the key used to limit a visitor address
+func remoteIPKey(remoteAddr string) string {
+	if ip := remoteIP(remoteAddr); ip != nil {
+		return ip.String()
+	}
+	return remoteAddr
+}
+
+// retryAfterSeconds formats a wait duration for the Retry-After header
+func retryAfterSeconds(d time.Duration) string {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// httpTunnelStats counts the requests of an HTTP tunnel
+type httpTunnelStats struct {
+	requests     int64
+	rateLimited  int64
+	inFlight     int64
+	peakInFlight int64
+}
+
+// begin counts a new request and returns the function to call when it is done
+func (s *httpTunnelStats) begin() func() {
+	atomic.AddInt64(&s.requests, 1)
+	current := atomic.AddInt64(&s.inFlight, 1)
+	for {
+		peak := atomic.LoadInt64(&s.peakInFlight)
+		if current <= peak || atomic.CompareAndSwapInt64(&s.peakInFlight, peak, current) {
+			break
+		}
+	}
+	return func() { atomic.AddInt64(&s.inFlight, -1) }
+}
+
+// snapshot returns the current counters
+func (s *httpTunnelStats) snapshot() model.TunnelStats {
+	return model.TunnelStats{
+		Requests:     atomic.LoadInt64(&s.requests),
+		RateLimited:  atomic.LoadInt64(&s.rateLimited),
+		InFlight:     atomic.LoadInt64(&s.inFlight),
+		PeakInFlight: atomic.LoadInt64(&s.peakInFlight),
+	}
+}
@@ -0,0 +1,195 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// newTestLimiter returns a limiter whose clock only moves when the returned
+// function is called
+func newTestLimiter(t *testing.T, config model.RateLimitConfig) (*requestLimiter, func(time.Duration)) {
+	t.Helper()
+
+	l, err := newRequestLimiter(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+// admit reports whether the limiter admits a request and releases it at once
+func admit(l *requestLimiter, remoteAddr string) (time.Duration, bool) {
+	done, retryAfter, ok := l.acquire(remoteAddr)
+	if ok {
+		done()
+	}
+	return retryAfter, ok
+}
+
+func TestRequestLimiterPerIP(t *testing.T) {
+	l, advance := newTestLimiter(t, model.RateLimitConfig{PerIPRate: 1.0 / 60, PerIPBurst: 2})
+
+	for i := 0; i < 2; i++ {
+		if _, ok := admit(l, "203.0.113.1:1000"); !ok {
+			t.Fatalf("request %d within the burst was rejected", i+1)
+		}
+	}
+
+	// Other ports of the same address share its bucket
+	retryAfter, ok := admit(l, "203.0.113.1:2000")
+	if ok || retryAfter != time.Minute {
+		t.Errorf("request over the burst = %v, %v; want rejected for 1m", retryAfter, ok)
+	}
+
+	if _, ok := admit(l, "198.51.100.7:1000"); !ok {
+		t.Error("another visitor address was rejected")
+	}
+
+	advance(time.Minute)
+	if _, ok := admit(l, "203.0.113.1:1000"); !ok {
+		t.Error("request after the refill was rejected")
+	}
+}
+
+func TestRequestLimiterTunnelRate(t *testing.T) {
+	l, advance := newTestLimiter(t, model.RateLimitConfig{Rate: 1.0 / 60, PerIPRate: 1.0 / 3600, PerIPBurst: 2})
+
+	if _, ok := admit(l, "203.0.113.1:1000"); !ok {
+		t.Fatal("first request was rejected")
+	}
+
+	// Visitors share the tunnel rate
+	if retryAfter, ok := admit(l, "198.51.100.7:1000"); ok || retryAfter != time.Minute {
+		t.Errorf("request over the tunnel rate = %v, %v; want rejected for 1m", retryAfter, ok)
+	}
+
+	// The rejected request gave its per-IP token back, so the visitor still
+	// has one once the tunnel rate allows another request
+	if _, ok := admit(l, "203.0.113.1:1000"); ok {
+		t.Fatal("request over the tunnel rate was admitted")
+	}
+	advance(time.Minute)
+	if retryAfter, ok := admit(l, "203.0.113.1:1000"); !ok {
+		t.Errorf("request after the tunnel refill was rejected for %v; the rejected request kept its per-IP token", retryAfter)
+	}
+}
+
+func TestRequestLimiterInFlight(t *testing.T) {
+	l, _ := newTestLimiter(t, model.RateLimitConfig{Rate: 1.0 / 60, Burst: 3, MaxInFlight: 2})
+
+	first, _, ok1 := l.acquire("203.0.113.1:1000")
+	_, _, ok2 := l.acquire("203.0.113.2:1000")
+	if !ok1 || !ok2 {
+		t.Fatal("requests within the in-flight cap were rejected")
+	}
+
+	retryAfter, ok := admit(l, "203.0.113.3:1000")
+	if ok || retryAfter != inFlightRetryAfter {
+		t.Errorf("request over the in-flight cap = %v, %v; want rejected for %v", retryAfter, ok, inFlightRetryAfter)
+	}
+
+	// Releasing a request frees its slot, and the rejected request did not
+	// use up the last token of the tunnel rate
+	first()
+	if _, ok := admit(l, "203.0.113.3:1000"); !ok {
+		t.Error("request after a slot was released was rejected")
+	}
+}
+
+func TestRequestLimiterSweepsIdleVisitors(t *testing.T) {
+	l, advance := newTestLimiter(t, model.RateLimitConfig{PerIPRate: 1})
+
+	admit(l, "203.0.113.1:1000")
+	advance(clientLimiterIdle / 2)
+	admit(l, "203.0.113.2:1000")
+	advance(clientLimiterIdle/2 + time.Second)
+	admit(l, "203.0.113.3:1000")
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, ok := l.clients["203.0.113.1"]; ok {
+		t.Error("limiter of an idle visitor was kept")
+	}
+	if len(l.clients) != 2 {
+		t.Errorf("%d visitor limiters kept, want 2", len(l.clients))
+	}
+}
+
+func TestNilRequestLimiterAdmitsEverything(t *testing.T) {
+	l, err := newRequestLimiter(nil)
+	if err != nil || l != nil {
+		t.Fatalf("newRequestLimiter(nil) = %v, %v", l, err)
+	}
+	if _, ok := admit(l, "203.0.113.1:1000"); !ok {
+		t.Error("nil limiter rejected a request")
+	}
+	if _, err := newRequestLimiter(&model.RateLimitConfig{Rate: -1}); err == nil {
+		t.Error("negative rate was accepted")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{0, "1"},
+		{300 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1200 * time.Millisecond, "2"},
+		{time.Minute, "60"},
+	}
+
+	for _, tt := range tests {
+		if got := retryAfterSeconds(tt.wait); got != tt.want {
+			t.Errorf("retryAfterSeconds(%v) = %q, want %q", tt.wait, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPRateLimitResponses(t *testing.T) {
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer local.Close()
+
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	if _, err := repo.client.SendRegisterTunnel(model.TunnelConfig{
+		Type:      model.TunnelTypeHTTP,
+		LocalPort: localPort(t, local),
+		RateLimit: &model.RateLimitConfig{PerIPRate: 1.0 / 30, MaxInFlight: 1},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(id string) *model.HTTPRequest {
+		return &model.HTTPRequest{ID: id, TunnelID: "tunnel-http", Method: http.MethodGet, URL: "/", RemoteAddr: "203.0.113.1:1000", Headers: http.Header{"Host": {"app.haxorport.online"}}}
+	}
+
+	if response := server.roundTrip(repo.client, request("first")); response.StatusCode != http.StatusOK {
+		t.Fatalf("first request: status %d, want %d", response.StatusCode, http.StatusOK)
+	}
+	response := server.roundTrip(repo.client, request("second"))
+	if response.StatusCode != http.StatusTooManyRequests || response.Headers.Get("Retry-After") != "30" {
+		t.Errorf("second request: status %d, Retry-After %q; want %d, 30", response.StatusCode, response.Headers.Get("Retry-After"), http.StatusTooManyRequests)
+	}
+
+	stats, _ := repo.client.TunnelStats("tunnel-http")
+	if stats.Requests != 2 || stats.RateLimited != 1 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want 2 requests, 1 rate limited, none in flight", stats)
+	}
+	repo.client.tunnelsMutex.RLock()
+	slots := len(repo.client.httpTunnels["tunnel-http"].limiter.inFlight)
+	repo.client.tunnelsMutex.RUnlock()
+	if slots != 0 {
+		t.Errorf("%d in-flight slots still taken after the requests", slots)
+	}
+}
@@ -0,0 +1,365 @@
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// mockReloadInterval is how often the mock file is checked for changes
+	mockReloadInterval = time.Second
+
+	// mockHeader tells visitors which mock rule answered a request
+	mockHeader = "X-Haxor-Mock"
+)
+
+// mockRule is a compiled mock rule
+type mockRule struct {
+	name    string
+	method  string
+	path    *regexp.Regexp
+	headers map[string]*regexp.Regexp
+	status  int
+	header  http.Header
+	body    []byte
+	file    string
+}
+
+// mockRules holds the mock rules of a tunnel, loaded from its mock file.
+// The file is reloaded whenever it changes, and can be replaced through the
+// admin API.
+type mockRules struct {
+	file string
+
+	mutex   sync.RWMutex
+	rules   []*mockRule
+	config  []model.MockRule
+	modTime time.Time
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// newMockRules loads the mock rules of a tunnel from file. It returns nil
+// when the tunnel has no mock file.
+func newMockRules(file string) (*mockRules, error) {
+	if file == "" {
+		return nil, nil
+	}
+
+	m := &mockRules{
+		file:   file,
+		stopCh: make(chan struct{}),
+	}
+	if _, err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// start watches the mock file for changes
+func (m *mockRules) start(logger port.Logger) {
+	if m == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(mockReloadInterval)
+		defer ticker.Stop()
+
+		// Log a failing file once instead of every second until it is fixed
+		var lastErr string
+		for {
+			select {
+			case <-ticker.C:
+				changed, err := m.reload()
+				switch {
+				case err != nil:
+					if err.Error() != lastErr {
+						logger.Warn("Gagal memuat ulang aturan mock dari %s, aturan lama tetap dipakai: %v", m.file, err)
+						lastErr = err.Error()
+					}
+				case changed:
+					logger.Info("Aturan mock dimuat ulang dari %s (%d aturan)", m.file, m.ruleCount())
+					lastErr = ""
+				}
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// close stops watching the mock file
+func (m *mockRules) close() {
+	if m != nil {
+		m.stopOnce.Do(func() { close(m.stopCh) })
+	}
+}
+
+// reload loads the mock file when it changed since it was last loaded
+func (m *mockRules) reload() (bool, error) {
+	info, err := os.Stat(m.file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read mock file: %v", err)
+	}
+
+	m.mutex.RLock()
+	unchanged := info.ModTime().Equal(m.modTime)
+	m.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(m.file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read mock file: %v", err)
+	}
+	config, rules, err := m.parse(data)
+	if err != nil {
+		return false, err
+	}
+
+	m.mutex.Lock()
+	m.rules, m.config = rules, config
+	m.modTime = info.ModTime()
+	m.mutex.Unlock()
+	return true, nil
+}
+
+// replace checks the rules of a mock file, then writes them to the mock
+// file and uses them at once. Invalid rules leave the file untouched.
+func (m *mockRules) replace(data []byte) error {
+	config, rules, err := m.parse(data)
+	if err != nil {
+		return err
+	}
+
+	// Write a temporary file first so that the watcher never sees a partly written file
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(m.file); err == nil {
+		mode = info.Mode().Perm()
+	}
+	temp, err := os.CreateTemp(filepath.Dir(m.file), "."+filepath.Base(m.file)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write mock file: %v", err)
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), mode)
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), m.file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write mock file: %v", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.rules, m.config = rules, config
+	if info, err := os.Stat(m.file); err == nil {
+		m.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// parse reads and compiles the rules of a mock file
+func (m *mockRules) parse(data []byte) ([]model.MockRule, []*mockRule, error) {
+	var mockFile model.MockFile
+	if err := yaml.Unmarshal(data, &mockFile); err != nil {
+		return nil, nil, fmt.Errorf("invalid mock file %s: %v", m.file, err)
+	}
+	rules, err := compileMockRules(mockFile.Mocks, filepath.Base(m.file), filepath.Dir(m.file))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mock file %s: %v", m.file, err)
+	}
+	return mockFile.Mocks, rules, nil
+}
+
+// ruleCount returns the number of loaded rules
+func (m *mockRules) ruleCount() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.rules)
+}
+
+// status returns the mock file and its rules
+func (m *mockRules) status() model.MockStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return model.MockStatus{File: m.file, Rules: append([]model.MockRule(nil), m.config...)}
+}
+
+// match returns the first rule matching a request, or nil
+func (m *mockRules) match(request *model.HTTPRequest) *mockRule {
+	if m == nil {
+		return nil
+	}
+
+	path := request.URL
+	if idx := strings.IndexAny(path, "?#"); idx >= 0 {
+		path = path[:idx]
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	for _, rule := range m.rules {
+		if rule.matches(request.Method, path, request.Headers) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// matches reports whether a request matches the rule
+func (r *mockRule) matches(method string, path string, header http.Header) bool {
+	if r.method != "" && !strings.EqualFold(r.method, method) {
+		return false
+	}
+	if r.path != nil && !r.path.MatchString(path) {
+		return false
+	}
+	for name, value := range r.headers {
+		values, ok := header[name]
+		if !ok {
+			return false
+		}
+		matched := false
+		for _, v := range values {
+			if value.MatchString(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// response builds the response of the rule
+func (r *mockRule) response(requestID string) (*model.HTTPResponse, error) {
+	body := r.body
+	if r.file != "" {
+		data, err := os.ReadFile(r.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mock response file: %v", err)
+		}
+		body = data
+	}
+
+	header := r.header.Clone()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", mockContentType(r.file, body))
+	}
+	header.Set(mockHeader, r.name)
+
+	return &model.HTTPResponse{
+		ID:         requestID,
+		StatusCode: r.status,
+		Headers:    header,
+		Body:       body,
+	}, nil
+}
+
+// compileMockRules compiles mock rules. Unnamed rules are named after source
+// and their position; relative response files are resolved against baseDir.
+func compileMockRules(rules []model.MockRule, source string, baseDir string) ([]*mockRule, error) {
+	compiled := make([]*mockRule, 0, len(rules))
+	for i, rule := range rules {
+		r := &mockRule{
+			name:    rule.Name,
+			method:  strings.ToUpper(strings.TrimSpace(rule.Method)),
+			headers: make(map[string]*regexp.Regexp, len(rule.Headers)),
+			status:  rule.Status,
+			header:  http.Header{},
+			body:    []byte(rule.Body),
+			file:    rule.File,
+		}
+		if r.name == "" {
+			r.name = fmt.Sprintf("%s#%d", source, i+1)
+		}
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		if r.status < 100 || r.status > 599 {
+			return nil, fmt.Errorf("mock %s: invalid status %d", r.name, r.status)
+		}
+		if r.file != "" && baseDir != "" && !filepath.IsAbs(r.file) {
+			r.file = filepath.Join(baseDir, r.file)
+		}
+
+		if rule.Path != "" {
+			pattern := rule.Path
+			if !strings.HasPrefix(pattern, "/") {
+				pattern = "/" + pattern
+			}
+			r.path = globRegexp(pattern, true)
+		}
+		for name, value := range rule.Headers {
+			r.headers[http.CanonicalHeaderKey(name)] = globRegexp(value, false)
+		}
+		for name, value := range rule.ResponseHeaders {
+			r.header.Set(name, value)
+		}
+
+		compiled = append(compiled, r)
+	}
+	return compiled, nil
+}
+
+// globRegexp compiles a glob into an anchored regular expression. For paths,
+// "*" matches within a path segment and "**" across segments; otherwise "*"
+// matches anything.
+func globRegexp(glob string, path bool) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case glob[i] == '*' && path && i+1 < len(glob) && glob[i+1] == '*':
+			expr.WriteString(".*")
+			i++
+		case glob[i] == '*' && path:
+			expr.WriteString("[^/]*")
+		case glob[i] == '*':
+			expr.WriteString(".*")
+		case glob[i] == '?' && path:
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// mockContentType guesses the content type of a mock response body
+func mockContentType(file string, body []byte) string {
+	if file != "" {
+		if contentType := mime.TypeByExtension(filepath.Ext(file)); contentType != "" {
+			return contentType
+		}
+	}
+	if len(body) > 0 && json.Valid(body) {
+		return "application/json"
+	}
+	return http.DetectContentType(body)
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// writeMockFile writes a mock file to a temporary directory
+func writeMockFile(t *testing.T, data string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "mocks.yaml")
+	if err := os.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestCompileMockRulesStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		want    int
+		wantErr bool
+	}{
+		{0, http.StatusOK, false},
+		{100, 100, false},
+		{204, 204, false},
+		{599, 599, false},
+		{99, 0, true},
+		{600, 0, true},
+		{999, 0, true},
+		{-1, 0, true},
+	}
+
+	for _, tt := range tests {
+		rules, err := compileMockRules([]model.MockRule{{Status: tt.status}}, "mock", "")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("status %d: error = %v, wantErr %v", tt.status, err, tt.wantErr)
+			continue
+		}
+		if err == nil && rules[0].status != tt.want {
+			t.Errorf("status %d: compiled %d, want %d", tt.status, rules[0].status, tt.want)
+		}
+	}
+}
+
+func TestMockRulesMatch(t *testing.T) {
+	m, err := newMockRules(writeMockFile(t, `
+mocks:
+  - name: health
+    method: GET
+    path: /api/health
+  - name: user
+    path: /api/users/*
+  - name: admin
+    path: /admin/**
+    headers:
+      X-Role: admin*
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.close()
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		header http.Header
+		want   string
+	}{
+		{"exact path", "GET", "/api/health?verbose=1", nil, "health"},
+		{"method mismatch", "POST", "/api/health", nil, ""},
+		{"segment glob", "DELETE", "/api/users/42", nil, "user"},
+		{"segment glob does not cross slashes", "GET", "/api/users/42/posts", nil, ""},
+		{"double star with header", "GET", "/admin/a/b", http.Header{"X-Role": {"administrator"}}, "admin"},
+		{"missing header", "GET", "/admin/a/b", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := m.match(&model.HTTPRequest{Method: tt.method, URL: tt.url, Headers: tt.header})
+			got := ""
+			if rule != nil {
+				got = rule.name
+			}
+			if got != tt.want {
+				t.Errorf("match(%s %s) = %q, want %q", tt.method, tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMockRulesReplace(t *testing.T) {
+	file := writeMockFile(t, "mocks:\n  - name: old\n    path: /old\n")
+	m, err := newMockRules(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.close()
+
+	// JSON is accepted as well, since it is valid YAML
+	replacement := `{"mocks": [{"name": "new", "path": "/new", "status": 201}]}`
+	if err := m.replace([]byte(replacement)); err != nil {
+		t.Fatal(err)
+	}
+	if rule := m.match(&model.HTTPRequest{Method: "GET", URL: "/new"}); rule == nil || rule.status != 201 {
+		t.Errorf("replaced rule not used: %+v", rule)
+	}
+	if rule := m.match(&model.HTTPRequest{Method: "GET", URL: "/old"}); rule != nil {
+		t.Error("old rule still used after the replace")
+	}
+	if data, _ := os.ReadFile(file); string(data) != replacement {
+		t.Errorf("mock file = %q, want the replacement", data)
+	}
+	info, _ := os.Stat(file)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mock file mode = %v, want 0600", info.Mode().Perm())
+	}
+	// The watcher does not load the file it just wrote again
+	if changed, err := m.reload(); changed || err != nil {
+		t.Errorf("reload() after replace = %v, %v; want no change", changed, err)
+	}
+
+	status := m.status()
+	if status.File != file || len(status.Rules) != 1 || status.Rules[0].Name != "new" {
+		t.Errorf("status() = %+v", status)
+	}
+
+	for _, invalid := range []string{"mocks: [", "mocks:\n  - status: 999\n"} {
+		if err := m.replace([]byte(invalid)); err == nil {
+			t.Errorf("replace(%q) succeeded, want error", invalid)
+		}
+	}
+	if data, _ := os.ReadFile(file); string(data) != replacement {
+		t.Errorf("invalid rules changed the mock file to %q", data)
+	}
+	if m.ruleCount() != 1 || m.match(&model.HTTPRequest{Method: "GET", URL: "/new"}) == nil {
+		t.Error("invalid rules replaced the rules in use")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(file)); len(entries) != 1 {
+		t.Errorf("%d files left in the mock file directory, want 1", len(entries))
+	}
+}
+
+func TestClientSetMockRules(t *testing.T) {
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	if _, err := repo.client.SendRegisterTunnel(model.TunnelConfig{
+		Type:      model.TunnelTypeHTTP,
+		LocalPort: closedPort(t),
+		MockFile:  writeMockFile(t, "mocks: []\n"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok, _ := repo.client.SetMockRules("tunnel-unknown", []byte("mocks: []")); ok {
+		t.Error("SetMockRules found a tunnel that is not registered")
+	}
+	if _, _, err := repo.client.SetMockRules("tunnel-http", []byte("mocks:\n  - path: /api/users/*\n    status: 999\n")); err == nil {
+		t.Error("SetMockRules accepted an invalid status")
+	}
+	status, ok, err := repo.client.SetMockRules("tunnel-http", []byte("mocks:\n  - name: user\n    path: /api/users/*\n    body: '{\"id\": 1}'\n"))
+	if !ok || err != nil || len(status.Rules) != 1 {
+		t.Fatalf("SetMockRules() = %+v, %v, %v", status, ok, err)
+	}
+
+	// The local port is closed, so only the new rule can answer
+	response := server.roundTrip(repo.client, &model.HTTPRequest{ID: "mocked", TunnelID: "tunnel-http", Method: http.MethodGet, URL: "/api/users/1", Headers: http.Header{"Host": {"app.haxorport.online"}}})
+	if response.StatusCode != http.StatusOK || response.Headers.Get(mockHeader) != "user" || string(response.Body) != `{"id": 1}` {
+		t.Errorf("response = %d %v %q, want the mock rule user", response.StatusCode, response.Headers, response.Body)
+	}
+	if got, _ := repo.client.MockRules("tunnel-http"); len(got.Rules) != 1 || got.Rules[0].Name != "user" {
+		t.Errorf("MockRules() = %+v", got)
+	}
+}
@@ -0,0 +1,457 @@
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+const (
+	// oidcCallbackPath and oidcLogoutPath are handled by the client and never
+	// forwarded to the local service
+	oidcCallbackPath = "/_haxor/oidc/callback"
+	oidcLogoutPath   = "/_haxor/oidc/logout"
+
+	oidcSessionCookie = "_haxor_session"
+	oidcStateCookie   = "_haxor_oidc_state"
+
+	oidcSessionDuration = 12 * time.Hour
+	oidcStateDuration   = 10 * time.Minute
+)
+
+// oidcIdentityHeaders are set on requests to the local service for logged in
+// visitors. Copies sent by the visitor are always removed.
+var oidcIdentityHeaders = []string{"X-Forwarded-User", "X-Forwarded-Email", "X-Forwarded-Preferred-Username"}
+
+// oidcProvider holds the endpoints of an OpenID Connect provider
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// oidcIdentity is the visitor identity stored in the session cookie
+type oidcIdentity struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email"`
+	Name    string `json:"name,omitempty"`
+	Expires int64  `json:"exp"`
+}
+
+// oidcState is stored in a cookie during the login redirect
+type oidcState struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Return   string `json:"return"`
+	Expires  int64  `json:"exp"`
+}
+
+// oidcAuthenticator runs the OpenID Connect authorization code flow for
+// visitors of an HTTP tunnel and keeps them logged in with a signed cookie.
+// Session and state cookies are signed with different keys, so that one
+// cannot be replayed as the other.
+type oidcAuthenticator struct {
+	auth       *model.TunnelAuth
+	sessionKey []byte
+	stateKey   []byte
+	client     *http.Client
+
+	mutex    sync.Mutex
+	provider *oidcProvider
+}
+
+// newOIDCAuthenticator validates the OIDC settings of a tunnel. Without a
+// cookie secret, a random key is used and sessions end when the client stops.
+func newOIDCAuthenticator(auth *model.TunnelAuth) (*oidcAuthenticator, error) {
+	if auth.Issuer == "" || auth.ClientID == "" {
+		return nil, fmt.Errorf("oidc auth requires an issuer and a client ID")
+	}
+
+	key := make([]byte, 32)
+	if auth.CookieSecret != "" {
+		sum := sha256.Sum256([]byte(auth.CookieSecret))
+		key = sum[:]
+	} else if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate cookie key: %v", err)
+	}
+
+	return &oidcAuthenticator{
+		auth:       auth,
+		sessionKey: cookieKey(key, oidcSessionCookie),
+		stateKey:   cookieKey(key, oidcStateCookie),
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// cookieKey derives the signing key of one cookie from the secret key
+func cookieKey(key []byte, cookie string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(cookie))
+	return mac.Sum(nil)
+}
+
+// authenticate returns the identity of a logged in visitor. Otherwise it
+// returns the status and headers of the response to send instead, such as
+// the redirect to the provider's login page or the end of the callback.
+func (a *oidcAuthenticator) authenticate(request *model.HTTPRequest, publicOrigin string) (*oidcIdentity, int, http.Header, error) {
+	u, err := url.ParseRequestURI(request.URL)
+	if err != nil {
+		return nil, http.StatusBadRequest, nil, err
+	}
+	secure := strings.HasPrefix(publicOrigin, "https://")
+
+	switch u.Path {
+	case oidcCallbackPath:
+		return a.callback(request, u.Query(), publicOrigin, secure)
+	case oidcLogoutPath:
+		headers := http.Header{}
+		headers.Set("Location", "/")
+		headers.Add("Set-Cookie", expiredCookie(oidcSessionCookie, secure))
+		return nil, http.StatusFound, headers, nil
+	}
+
+	var identity oidcIdentity
+	if value, ok := requestCookie(request.Headers, oidcSessionCookie); ok && a.verify(a.sessionKey, value, &identity) &&
+		identity.Expires > time.Now().Unix() && identity.Subject != "" && identity.Email != "" && a.allowed(identity.Email) {
+		return &identity, 0, nil, nil
+	}
+
+	// Only page loads are redirected, API calls get a plain 401
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return nil, http.StatusUnauthorized, nil, nil
+	}
+	return a.login(request.URL, publicOrigin, secure)
+}
+
+// login redirects the visitor to the provider's authorization endpoint
+func (a *oidcAuthenticator) login(returnURI string, publicOrigin string, secure bool) (*oidcIdentity, int, http.Header, error) {
+	provider, err := a.discover()
+	if err != nil {
+		return nil, http.StatusBadGateway, nil, err
+	}
+
+	state := oidcState{
+		State:    randomToken(),
+		Verifier: randomToken(),
+		Return:   returnURI,
+		Expires:  time.Now().Add(oidcStateDuration).Unix(),
+	}
+	challenge := sha256.Sum256([]byte(state.Verifier))
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", a.auth.ClientID)
+	query.Set("redirect_uri", publicOrigin+oidcCallbackPath)
+	query.Set("scope", "openid email profile")
+	query.Set("state", state.State)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	headers := http.Header{}
+	headers.Set("Location", provider.AuthorizationEndpoint+separator+query.Encode())
+	headers.Add("Set-Cookie", newCookie(oidcStateCookie, a.sign(a.stateKey, state), oidcStateDuration, secure))
+	return nil, http.StatusFound, headers, nil
+}
+
+// callback completes the login: it checks the state, exchanges the code for
+// an access token, reads the visitor's profile and issues the session cookie
+func (a *oidcAuthenticator) callback(request *model.HTTPRequest, query url.Values, publicOrigin string, secure bool) (*oidcIdentity, int, http.Header, error) {
+	if errCode := query.Get("error"); errCode != "" {
+		return nil, http.StatusForbidden, nil, fmt.Errorf("login failed: %s %s", errCode, query.Get("error_description"))
+	}
+
+	var state oidcState
+	value, ok := requestCookie(request.Headers, oidcStateCookie)
+	if !ok || !a.verify(a.stateKey, value, &state) || state.Expires < time.Now().Unix() || !secureCompare(state.State, query.Get("state")) {
+		return nil, http.StatusBadRequest, nil, fmt.Errorf("invalid login state")
+	}
+
+	provider, err := a.discover()
+	if err != nil {
+		return nil, http.StatusBadGateway, nil, err
+	}
+
+	accessToken, err := a.exchange(provider, query.Get("code"), state.Verifier, publicOrigin+oidcCallbackPath)
+	if err != nil {
+		return nil, http.StatusBadGateway, nil, err
+	}
+
+	identity, err := a.userinfo(provider, accessToken)
+	if err != nil {
+		return nil, http.StatusBadGateway, nil, err
+	}
+	if !a.allowed(identity.Email) {
+		return nil, http.StatusForbidden, nil, fmt.Errorf("%s is not allowed", identity.Email)
+	}
+	identity.Expires = time.Now().Add(oidcSessionDuration).Unix()
+
+	// Only redirect back to paths on the tunnel itself
+	returnURI := state.Return
+	if !strings.HasPrefix(returnURI, "/") || strings.HasPrefix(returnURI, "//") {
+		returnURI = "/"
+	}
+
+	headers := http.Header{}
+	headers.Set("Location", returnURI)
+	headers.Add("Set-Cookie", newCookie(oidcSessionCookie, a.sign(a.sessionKey, identity), oidcSessionDuration, secure))
+	headers.Add("Set-Cookie", expiredCookie(oidcStateCookie, secure))
+	return nil, http.StatusFound, headers, nil
+}
+
+// discover fetches the provider metadata from the issuer's discovery
+// document. Successful results are cached.
+func (a *oidcAuthenticator) discover() (*oidcProvider, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.provider != nil {
+		return a.provider, nil
+	}
+
+	issuer := strings.TrimSuffix(a.auth.Issuer, "/")
+	var provider oidcProvider
+	if err := a.getJSON(issuer+"/.well-known/openid-configuration", "", &provider); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider: %v", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc provider issuer mismatch: %s", provider.Issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("oidc provider does not advertise the required endpoints")
+	}
+
+	a.provider = &provider
+	return a.provider, nil
+}
+
+// exchange trades an authorization code for an access token
+func (a *oidcAuthenticator) exchange(provider *oidcProvider, code string, verifier string, redirectURI string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequest(http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.auth.ClientID), url.QueryEscape(a.auth.ClientSecret))
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := a.doJSON(req, &token); err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("failed to exchange authorization code: no access token")
+	}
+	return token.AccessToken, nil
+}
+
+// userinfo reads the visitor's profile from the provider
+func (a *oidcAuthenticator) userinfo(provider *oidcProvider, accessToken string) (*oidcIdentity, error) {
+	var claims struct {
+		Subject           string `json:"sub"`
+		Email             string `json:"email"`
+		EmailVerified     *bool  `json:"email_verified"`
+		Name              string `json:"name"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := a.getJSON(provider.UserinfoEndpoint, accessToken, &claims); err != nil {
+		return nil, fmt.Errorf("failed to fetch user info: %v", err)
+	}
+	if claims.Subject == "" || claims.Email == "" {
+		return nil, fmt.Errorf("user info has no subject or email")
+	}
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, fmt.Errorf("email %s is not verified", claims.Email)
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Name
+	}
+	return &oidcIdentity{Subject: claims.Subject, Email: claims.Email, Name: name}, nil
+}
+
+// allowed reports whether an email matches the allowed emails or domains.
+// Every verified email is allowed when neither list is set.
+func (a *oidcAuthenticator) allowed(email string) bool {
+	if len(a.auth.AllowedEmails) == 0 && len(a.auth.AllowedDomains) == 0 {
+		return true
+	}
+	for _, allowed := range a.auth.AllowedEmails {
+		if strings.EqualFold(email, allowed) {
+			return true
+		}
+	}
+	if idx := strings.LastIndex(email, "@"); idx >= 0 {
+		domain := email[idx+1:]
+		for _, allowed := range a.auth.AllowedDomains {
+			if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getJSON sends a GET request, with a bearer token if one is given, and
+// decodes the JSON response into v
+func (a *oidcAuthenticator) getJSON(rawURL string, bearer string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return a.doJSON(req, v)
+}
+
+// doJSON sends a request and decodes the JSON response into v
+func (a *oidcAuthenticator) doJSON(req *http.Request, v interface{}) error {
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// sign encodes v as a cookie value signed with HMAC-SHA256
+func (a *oidcAuthenticator) sign(key []byte, v interface{}) string {
+	payload, _ := json.Marshal(v)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature of a cookie value created by sign with the
+// same key and decodes it into v
+func (a *oidcAuthenticator) verify(key []byte, value string, v interface{}) bool {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(payload, v) == nil
+}
+
+// newCookie formats a Set-Cookie header value for one of the client's cookies
+func newCookie(name string, value string, maxAge time.Duration, secure bool) string {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	return cookie.String()
+}
+
+// expiredCookie formats a Set-Cookie header value that removes a cookie
+func expiredCookie(name string, secure bool) string {
+	return newCookie(name, "", -time.Second, secure)
+}
+
+// setIdentityHeaders replaces the identity headers of an upstream request
+// with those of the logged in visitor
+func setIdentityHeaders(header http.Header, identity *oidcIdentity) {
+	for _, name := range oidcIdentityHeaders {
+		header.Del(name)
+	}
+	if identity == nil {
+		return
+	}
+	header.Set("X-Forwarded-User", identity.Subject)
+	header.Set("X-Forwarded-Email", identity.Email)
+	if identity.Name != "" {
+		header.Set("X-Forwarded-Preferred-Username", identity.Name)
+	}
+}
+
+// requestCookie returns the value of a cookie sent by the visitor
+func requestCookie(header http.Header, name string) (string, bool) {
+	cookie, err := (&http.Request{Header: header}).Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return cookie.Value, true
+}
+
+// removeCookies removes the named cookies from the Cookie header of a
+// request so they are not forwarded to the local service
+func removeCookies(header http.Header, names ...string) {
+	cookies := (&http.Request{Header: header}).Cookies()
+	header.Del("Cookie")
+
+	var kept []string
+	for _, cookie := range cookies {
+		drop := false
+		for _, name := range names {
+			if cookie.Name == name {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, cookie.Name+"="+cookie.Value)
+		}
+	}
+	if len(kept) > 0 {
+		header.Set("Cookie", strings.Join(kept, "; "))
+	}
+}
+
+// randomToken returns a random URL-safe token
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
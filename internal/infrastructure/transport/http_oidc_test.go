@@ -0,0 +1,241 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+const testPublicOrigin = "https://app.haxorport.online"
+
+// newTestOIDCProvider starts a provider that issues one code for the given
+// email and checks the PKCE verifier of the login that requested it
+func newTestOIDCProvider(t *testing.T, email string) *httptest.Server {
+	t.Helper()
+
+	var challenge string
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcProvider{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			UserinfoEndpoint:      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		challenge = r.URL.Query().Get("code_challenge")
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if user != "client" || pass != "secret" || r.PostFormValue("code") != "good-code" ||
+			base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"sub":            "user-1",
+			"email":          email,
+			"email_verified": true,
+		})
+	})
+	return server
+}
+
+func newTestOIDCAuthenticator(t *testing.T, issuer string) *oidcAuthenticator {
+	t.Helper()
+	a, err := newOIDCAuthenticator(&model.TunnelAuth{
+		Type:           model.AuthTypeOIDC,
+		Issuer:         issuer,
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		AllowedDomains: []string{"example.com"},
+		CookieSecret:   "cookie-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// cookieValue returns the value of a cookie set in response headers
+func cookieValue(t *testing.T, headers http.Header, name string) string {
+	t.Helper()
+	for _, cookie := range (&http.Response{Header: headers}).Cookies() {
+		if cookie.Name == name && cookie.Value != "" {
+			return cookie.Value
+		}
+	}
+	t.Fatalf("no %s cookie in %v", name, headers["Set-Cookie"])
+	return ""
+}
+
+func oidcRequest(method string, uri string, cookies ...*http.Cookie) *model.HTTPRequest {
+	req := &http.Request{Header: http.Header{}}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return &model.HTTPRequest{Method: method, URL: uri, Headers: req.Header}
+}
+
+// testOIDCLogin runs the redirect to the provider and returns the state cookie and state
+func testOIDCLogin(t *testing.T, a *oidcAuthenticator) (string, string) {
+	t.Helper()
+
+	identity, status, headers, err := a.authenticate(oidcRequest(http.MethodGet, "/dashboard?tab=1"), testPublicOrigin)
+	if err != nil || identity != nil || status != http.StatusFound {
+		t.Fatalf("authenticate without session = %v, %d, %v; want redirect", identity, status, err)
+	}
+
+	location, err := url.Parse(headers.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := location.Query()
+	if query.Get("redirect_uri") != testPublicOrigin+oidcCallbackPath || query.Get("client_id") != "client" {
+		t.Fatalf("unexpected authorization request %s", location)
+	}
+
+	// Let the provider record the PKCE challenge
+	resp, err := http.Get(location.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	return cookieValue(t, headers, oidcStateCookie), query.Get("state")
+}
+
+func TestOIDCLoginAndSession(t *testing.T) {
+	provider := newTestOIDCProvider(t, "alice@example.com")
+	a := newTestOIDCAuthenticator(t, provider.URL)
+
+	stateCookie, state := testOIDCLogin(t, a)
+
+	callback := oidcCallbackPath + "?code=good-code&state=" + url.QueryEscape(state)
+	_, status, headers, err := a.authenticate(oidcRequest(http.MethodGet, callback,
+		&http.Cookie{Name: oidcStateCookie, Value: stateCookie}), testPublicOrigin)
+	if err != nil || status != http.StatusFound {
+		t.Fatalf("callback = %d, %v; want redirect", status, err)
+	}
+	if got := headers.Get("Location"); got != "/dashboard?tab=1" {
+		t.Errorf("callback Location = %q, want /dashboard?tab=1", got)
+	}
+	session := cookieValue(t, headers, oidcSessionCookie)
+
+	identity, status, _, err := a.authenticate(oidcRequest(http.MethodGet, "/dashboard",
+		&http.Cookie{Name: oidcSessionCookie, Value: session}), testPublicOrigin)
+	if err != nil || identity == nil {
+		t.Fatalf("authenticate with session = %v, %d, %v; want identity", identity, status, err)
+	}
+	if identity.Subject != "user-1" || identity.Email != "alice@example.com" {
+		t.Errorf("identity = %+v", identity)
+	}
+}
+
+func TestOIDCCallbackRejections(t *testing.T) {
+	provider := newTestOIDCProvider(t, "alice@example.com")
+	a := newTestOIDCAuthenticator(t, provider.URL)
+
+	stateCookie, state := testOIDCLogin(t, a)
+
+	tests := []struct {
+		name       string
+		query      string
+		cookie     string
+		wantStatus int
+	}{
+		{"missing state cookie", "?code=good-code&state=" + url.QueryEscape(state), "", http.StatusBadRequest},
+		{"wrong state", "?code=good-code&state=other", stateCookie, http.StatusBadRequest},
+		{"tampered state cookie", "?code=good-code&state=" + url.QueryEscape(state), stateCookie + "x", http.StatusBadRequest},
+		{"bad code", "?code=bad-code&state=" + url.QueryEscape(state), stateCookie, http.StatusBadGateway},
+		{"provider error", "?error=access_denied", stateCookie, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cookies []*http.Cookie
+			if tt.cookie != "" {
+				cookies = append(cookies, &http.Cookie{Name: oidcStateCookie, Value: tt.cookie})
+			}
+			identity, status, _, _ := a.authenticate(oidcRequest(http.MethodGet, oidcCallbackPath+tt.query, cookies...), testPublicOrigin)
+			if identity != nil || status != tt.wantStatus {
+				t.Errorf("callback = %v, %d; want %d", identity, status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestOIDCCallbackRejectsDisallowedEmail(t *testing.T) {
+	provider := newTestOIDCProvider(t, "mallory@other.com")
+	a := newTestOIDCAuthenticator(t, provider.URL)
+
+	stateCookie, state := testOIDCLogin(t, a)
+
+	callback := oidcCallbackPath + "?code=good-code&state=" + url.QueryEscape(state)
+	_, status, _, _ := a.authenticate(oidcRequest(http.MethodGet, callback,
+		&http.Cookie{Name: oidcStateCookie, Value: stateCookie}), testPublicOrigin)
+	if status != http.StatusForbidden {
+		t.Errorf("callback status = %d, want %d", status, http.StatusForbidden)
+	}
+}
+
+func TestOIDCRejectsForgedSessions(t *testing.T) {
+	provider := newTestOIDCProvider(t, "alice@example.com")
+	a := newTestOIDCAuthenticator(t, provider.URL)
+
+	stateCookie, _ := testOIDCLogin(t, a)
+	expires := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"state cookie replayed as session", stateCookie},
+		{"session signed with state key", a.sign(a.stateKey, oidcIdentity{Subject: "user-1", Email: "alice@example.com", Expires: expires})},
+		{"empty subject", a.sign(a.sessionKey, oidcIdentity{Email: "alice@example.com", Expires: expires})},
+		{"empty email", a.sign(a.sessionKey, oidcIdentity{Subject: "user-1", Expires: expires})},
+		{"expired", a.sign(a.sessionKey, oidcIdentity{Subject: "user-1", Email: "alice@example.com", Expires: time.Now().Add(-time.Minute).Unix()})},
+		{"email no longer allowed", a.sign(a.sessionKey, oidcIdentity{Subject: "user-2", Email: "bob@other.com", Expires: expires})},
+		{"unsigned", base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user-1","email":"alice@example.com"}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, status, _, _ := a.authenticate(oidcRequest(http.MethodGet, "/dashboard",
+				&http.Cookie{Name: oidcSessionCookie, Value: tt.value}), testPublicOrigin)
+			if identity != nil {
+				t.Fatalf("authenticate accepted forged session: %+v", identity)
+			}
+			if status != http.StatusFound {
+				t.Errorf("status = %d, want redirect to login", status)
+			}
+		})
+	}
+
+	// API calls without a session get a 401 instead of a redirect
+	_, status, _, _ := a.authenticate(oidcRequest(http.MethodPost, "/api",
+		&http.Cookie{Name: oidcSessionCookie, Value: stateCookie}), testPublicOrigin)
+	if status != http.StatusUnauthorized {
+		t.Errorf("POST status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
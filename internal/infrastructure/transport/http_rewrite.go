@@ -0,0 +1,369 @@
+package transport
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html"
+)
+
+// urlAttributes lists HTML attributes that hold a single URL
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"poster":     true,
+	"data":       true,
+	"cite":       true,
+	"background": true,
+	"manifest":   true,
+	"longdesc":   true,
+}
+
+// cssURLPattern matches url(...) references in CSS
+var cssURLPattern = regexp.MustCompile(`url\(\s*(['"]?)([^'")]*)(['"]?)\s*\)`)
+
+// urlRewriter replaces URLs pointing at the local service with URLs on the
+// tunnel's public origin. Relative URLs are left untouched since browsers
+// already resolve them against the public origin.
+type urlRewriter struct {
+	// localHosts are the host[:port] values the local service may use for itself
+	localHosts []string
+	// localOrigin matches absolute local origins inside free text
+	localOrigin *regexp.Regexp
+	// publicScheme and publicHost form the public origin of the tunnel
+	publicScheme string
+	publicHost   string
+}
+
+// newURLRewriter creates a rewriter for local hosts such as "localhost:3000"
+// (the upstream address and, if different, the Host header sent to it)
+func newURLRewriter(publicScheme string, publicHost string, localHosts ...string) *urlRewriter {
+	var hosts []string
+	for _, localHost := range localHosts {
+		if localHost == "" || strings.EqualFold(localHost, publicHost) {
+			continue
+		}
+		hosts = append(hosts, localHost)
+		if host, port, err := net.SplitHostPort(localHost); err == nil {
+			switch host {
+			case "localhost":
+				hosts = append(hosts, net.JoinHostPort("127.0.0.1", port))
+			case "127.0.0.1":
+				hosts = append(hosts, net.JoinHostPort("localhost", port))
+			}
+		}
+	}
+
+	// An empty alternative would match every origin, so use a pattern that never matches
+	quoted := []string{`[^\s\S]`}
+	for _, host := range hosts {
+		quoted = append(quoted, regexp.QuoteMeta(host))
+	}
+
+	return &urlRewriter{
+		localHosts:   hosts,
+		localOrigin:  regexp.MustCompile(`(?i)https?://(?:` + strings.Join(quoted, "|") + `)\b`),
+		publicScheme: publicScheme,
+		publicHost:   publicHost,
+	}
+}
+
+// publicOrigin returns the origin visitors use to reach the tunnel
+func (r *urlRewriter) publicOrigin() string {
+	return r.publicScheme + "://" + r.publicHost
+}
+
+// rewriteURL rewrites an absolute or protocol-relative URL pointing at the
+// local service. Any other URL is returned unchanged.
+func (r *urlRewriter) rewriteURL(raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	for _, host := range r.localHosts {
+		for _, prefix := range []string{"http://" + host, "https://" + host} {
+			if rest, ok := cutOriginPrefix(trimmed, prefix); ok {
+				return r.publicOrigin() + rest
+			}
+		}
+		if rest, ok := cutOriginPrefix(trimmed, "//"+host); ok {
+			return "//" + r.publicHost + rest
+		}
+	}
+	return raw
+}
+
+// cutOriginPrefix removes an origin prefix from s when the prefix is followed
+// by the end of the string or a path, query or fragment
+func cutOriginPrefix(s string, prefix string) (string, bool) {
+	if len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := s[len(prefix):]
+	if rest != "" && !strings.ContainsAny(rest[:1], "/?#") {
+		return "", false
+	}
+	return rest, true
+}
+
+// rewriteText replaces local origins inside free text such as inline scripts
+func (r *urlRewriter) rewriteText(text string) string {
+	return r.localOrigin.ReplaceAllLiteralString(text, r.publicOrigin())
+}
+
+// rewriteSrcset rewrites every candidate URL of a srcset attribute
+func (r *urlRewriter) rewriteSrcset(srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = r.rewriteURL(fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// rewriteCSS rewrites url(...) references and absolute local URLs in CSS
+func (r *urlRewriter) rewriteCSS(css string) string {
+	css = cssURLPattern.ReplaceAllStringFunc(css, func(match string) string {
+		parts := cssURLPattern.FindStringSubmatch(match)
+		return "url(" + parts[1] + r.rewriteURL(parts[2]) + parts[3] + ")"
+	})
+	return r.rewriteText(css)
+}
+
+// rewriteHTML rewrites URLs in HTML attributes, <base>, inline CSS and inline
+// scripts. Tokens that do not change are copied byte for byte.
+func (r *urlRewriter) rewriteHTML(body []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(body))
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	rawTextTag := ""
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return out.Bytes()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			// Raw must be copied before Token reuses the tokenizer buffer
+			raw := append([]byte(nil), z.Raw()...)
+			token := z.Token()
+			if tt == html.StartTagToken && (token.Data == "script" || token.Data == "style") {
+				rawTextTag = token.Data
+			}
+			if r.rewriteAttributes(&token) {
+				out.WriteString(token.String())
+			} else {
+				out.Write(raw)
+			}
+		case html.EndTagToken:
+			rawTextTag = ""
+			out.Write(z.Raw())
+		case html.TextToken:
+			raw := string(z.Raw())
+			switch rawTextTag {
+			case "style":
+				raw = r.rewriteCSS(raw)
+			case "script":
+				raw = r.rewriteText(raw)
+			}
+			out.WriteString(raw)
+		default:
+			out.Write(z.Raw())
+		}
+	}
+}
+
+// rewriteAttributes rewrites the URL attributes of a tag and reports whether
+// anything changed
+func (r *urlRewriter) rewriteAttributes(token *html.Token) bool {
+	changed := false
+	for i, attr := range token.Attr {
+		value := attr.Val
+		switch {
+		case urlAttributes[attr.Key]:
+			value = r.rewriteURL(attr.Val)
+		case attr.Key == "srcset" || attr.Key == "imagesrcset":
+			value = r.rewriteSrcset(attr.Val)
+		case attr.Key == "style":
+			value = r.rewriteCSS(attr.Val)
+		case attr.Key == "content" && token.Data == "meta":
+			value = r.rewriteText(attr.Val)
+		}
+		if value != attr.Val {
+			token.Attr[i].Val = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rewriteBody rewrites an HTML or CSS response body, decoding and re-encoding
+// it when it is compressed. Bodies with other content types or unsupported
+// encodings are returned unchanged. Compressed bodies that decode to more
+// than limit bytes, or that do not fit the memory budget, are not rewritten.
+func (r *urlRewriter) rewriteBody(header http.Header, body []byte, limit int64, reservation *bodyReservation) ([]byte, error) {
+	contentType := header.Get("Content-Type")
+	isHTML := strings.Contains(contentType, "text/html")
+	isCSS := strings.Contains(contentType, "text/css")
+	if !isHTML && !isCSS {
+		return body, nil
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Encoding")))
+	decoded, err := decodeBody(encoding, body, limit, reservation)
+	if err != nil {
+		return nil, err
+	}
+	if decoded == nil {
+		return body, nil
+	}
+
+	if isHTML {
+		decoded = r.rewriteHTML(decoded)
+	} else {
+		decoded = []byte(r.rewriteCSS(string(decoded)))
+	}
+
+	encoded, err := encodeBody(encoding, decoded)
+	if err != nil {
+		return nil, err
+	}
+	header.Set("Content-Length", strconv.Itoa(len(encoded)))
+	return encoded, nil
+}
+
+// rewriteHeaders rewrites redirect and cookie headers pointing at the local
+// service so that they work on the public origin: Location, Content-Location
+// and Refresh URLs, and the Domain and Secure attributes of Set-Cookie
+func (r *urlRewriter) rewriteHeaders(header http.Header) {
+	for _, name := range []string{"Location", "Content-Location"} {
+		if value := header.Get(name); value != "" {
+			header.Set(name, r.rewriteURL(value))
+		}
+	}
+
+	if refresh := header.Get("Refresh"); refresh != "" {
+		header.Set("Refresh", r.rewriteText(refresh))
+	}
+
+	cookies := header.Values("Set-Cookie")
+	for i, cookie := range cookies {
+		cookies[i] = r.rewriteCookie(cookie)
+	}
+}
+
+// rewriteCookie replaces a Domain attribute naming a local host with the
+// public host, and drops the Secure attribute when the public origin is
+// plain HTTP since browsers would reject the cookie otherwise
+func (r *urlRewriter) rewriteCookie(cookie string) string {
+	parts := strings.Split(cookie, ";")
+	kept := parts[:1]
+	for _, part := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch {
+		case strings.EqualFold(name, "domain") && r.isLocalDomain(value):
+			part = " Domain=" + hostWithoutPort(r.publicHost)
+		case strings.EqualFold(name, "secure") && r.publicScheme != "https":
+			continue
+		}
+		kept = append(kept, part)
+	}
+	return strings.Join(kept, ";")
+}
+
+// isLocalDomain reports whether a cookie domain names one of the local hosts
+func (r *urlRewriter) isLocalDomain(domain string) bool {
+	domain = strings.TrimPrefix(strings.TrimSpace(domain), ".")
+	for _, host := range r.localHosts {
+		if strings.EqualFold(domain, hostWithoutPort(host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostWithoutPort strips the port from host[:port]
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// decodeBody decodes a body with the given Content-Encoding, reserving memory
+// for the decoded body as it is read. It returns errBodyTooLarge when the
+// decoded body exceeds limit bytes, and nil without an error for encodings
+// that are not supported.
+func decodeBody(encoding string, body []byte, limit int64, reservation *bodyReservation) ([]byte, error) {
+	var reader io.Reader
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip body: %v", err)
+		}
+		defer gz.Close()
+		reader = gz
+	case "deflate":
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode deflate body: %v", err)
+		}
+		defer zr.Close()
+		reader = zr
+	case "br":
+		reader = brotli.NewReader(bytes.NewReader(body))
+	default:
+		return nil, nil
+	}
+
+	decoded, err := readResponseBody(io.LimitReader(reader, limit+1), -1, limit, reservation)
+	if err == errBodyTooLarge || err == errMemoryBudget {
+		return nil, fmt.Errorf("%s body not decoded: %w", encoding, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s body: %v", encoding, err)
+	}
+	return decoded, nil
+}
+
+// encodeBody encodes a body with the given Content-Encoding
+func encodeBody(encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer = zlib.NewWriter(&buf)
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+
+	if _, err := writer.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to encode %s body: %v", encoding, err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode %s body: %v", encoding, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRewriteBodyDecodesCompressedBodies(t *testing.T) {
+	page := []byte(`<a href="http://localhost:3000/docs">docs</a>`)
+
+	for _, encoding := range []string{"identity", "gzip", "deflate", "br"} {
+		t.Run(encoding, func(t *testing.T) {
+			body, err := encodeBody(encoding, page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := http.Header{"Content-Type": {"text/html"}, "Content-Encoding": {encoding}}
+			reservation := newMemoryBudget(1 << 20).reservation()
+			defer reservation.release()
+
+			rewriter := newURLRewriter("https", "app.haxorport.online", "localhost:3000")
+			rewritten, err := rewriter.rewriteBody(header, body, 1<<20, reservation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := decodeBody(encoding, rewritten, 1<<20, reservation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(decoded), "https://app.haxorport.online/docs") {
+				t.Errorf("rewritten body = %s", decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyLimits(t *testing.T) {
+	// A small compressed body that expands far beyond the limit
+	bomb := bytes.Repeat([]byte("a"), 4<<20)
+
+	for _, encoding := range []string{"gzip", "deflate", "br"} {
+		t.Run(encoding, func(t *testing.T) {
+			body, err := encodeBody(encoding, bomb)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			budget := newMemoryBudget(1 << 30)
+			reservation := budget.reservation()
+			_, err = decodeBody(encoding, body, 256<<10, reservation)
+			if !errors.Is(err, errBodyTooLarge) {
+				t.Errorf("decodeBody over limit: error = %v, want %v", err, errBodyTooLarge)
+			}
+			if reservation.size > 256<<10+2*bodyReadChunk {
+				t.Errorf("reserved %d bytes for a body limited to %d", reservation.size, 256<<10)
+			}
+			reservation.release()
+
+			small := newMemoryBudget(64 << 10).reservation()
+			_, err = decodeBody(encoding, body, 8<<20, small)
+			if !errors.Is(err, errMemoryBudget) {
+				t.Errorf("decodeBody over budget: error = %v, want %v", err, errMemoryBudget)
+			}
+			small.release()
+		})
+	}
+}
+
+func TestRewriteBodyPassesLargeBodiesThrough(t *testing.T) {
+	page := append([]byte(`<a href="http://localhost:3000/">home</a>`), bytes.Repeat([]byte(" "), 1<<20)...)
+	body, err := encodeBody("gzip", page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}}
+	reservation := newMemoryBudget(1 << 30).reservation()
+	defer reservation.release()
+
+	rewriter := newURLRewriter("https", "app.haxorport.online", "localhost:3000")
+	if _, err := rewriter.rewriteBody(header, body, 64<<10, reservation); !errors.Is(err, errBodyTooLarge) {
+		t.Errorf("rewriteBody error = %v, want %v", err, errBodyTooLarge)
+	}
+}
@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// httpRoute is a compiled model.TunnelRoute
+type httpRoute struct {
+	prefix      string
+	regex       *regexp.Regexp
+	target      string
+	stripPrefix bool
+}
+
+// httpRouter selects the local target of a request based on its path.
+// Regex routes are tried first in declaration order, then the longest
+// matching path prefix wins.
+type httpRouter struct {
+	regexRoutes  []httpRoute
+	prefixRoutes []httpRoute
+}
+
+// newHTTPRouter compiles the routes of a tunnel. Targets without a scheme use scheme.
+func newHTTPRouter(routes []model.TunnelRoute, scheme string) (*httpRouter, error) {
+	router := &httpRouter{}
+
+	for _, route := range routes {
+		if route.Target == "" {
+			return nil, fmt.Errorf("route %s has no target", route)
+		}
+		compiled := httpRoute{
+			target:      upstreamURL(route.Target, scheme),
+			stripPrefix: route.StripPrefix,
+		}
+
+		if route.Regex != "" {
+			re, err := regexp.Compile(route.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex in route %s: %v", route, err)
+			}
+			compiled.regex = re
+			router.regexRoutes = append(router.regexRoutes, compiled)
+			continue
+		}
+
+		compiled.prefix = strings.TrimRight(strings.TrimSuffix(route.Path, "*"), "/")
+		router.prefixRoutes = append(router.prefixRoutes, compiled)
+	}
+
+	// Longest prefix first
+	sort.SliceStable(router.prefixRoutes, func(i, j int) bool {
+		return len(router.prefixRoutes[i].prefix) > len(router.prefixRoutes[j].prefix)
+	})
+
+	return router, nil
+}
+
+// match returns the target base URL for path and the path to forward.
+// ok is false when no route matches.
+func (r *httpRouter) match(path string) (target string, forwardPath string, ok bool) {
+	for _, route := range r.regexRoutes {
+		loc := route.regex.FindStringIndex(path)
+		if loc == nil {
+			continue
+		}
+		if route.stripPrefix && loc[0] == 0 {
+			return route.target, ensureLeadingSlash(path[loc[1]:]), true
+		}
+		return route.target, path, true
+	}
+
+	for _, route := range r.prefixRoutes {
+		if route.prefix != "" && path != route.prefix && !strings.HasPrefix(path, route.prefix+"/") {
+			continue
+		}
+		if route.stripPrefix {
+			return route.target, ensureLeadingSlash(strings.TrimPrefix(path, route.prefix)), true
+		}
+		return route.target, path, true
+	}
+
+	return "", path, false
+}
+
+// upstreamURL turns a target such as "8080", "localhost:8080",
+// "https://localhost:8443" or "unix:///run/app.sock" into a base URL, using
+// scheme when the target has none
+func upstreamURL(target string, scheme string) string {
+	if path := model.UnixSocketPath(target); path != "" {
+		return scheme + "://" + unixSocketHost(path)
+	}
+	if strings.Contains(target, "://") {
+		return strings.TrimRight(target, "/")
+	}
+	trimmed := strings.TrimPrefix(target, ":")
+	if _, err := strconv.Atoi(trimmed); err == nil {
+		target = "localhost:" + trimmed
+	}
+	return scheme + "://" + target
+}
+
+// ensureLeadingSlash makes sure a path starts with "/"
+func ensureLeadingSlash(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return "/" + path
+	}
+	return path
+}
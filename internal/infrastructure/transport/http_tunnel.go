@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+)
+
+// httpTunnel holds the configuration and runtime state of a registered HTTP tunnel
+type httpTunnel struct {
+	config   model.TunnelConfig
+	scheme   string
+	upstream string
+	router   *httpRouter
+	pool     *upstreamPool
+	oidc     *oidcAuthenticator
+	jwt      *jwtVerifier
+	webhook  *webhookVerifier
+	policy   *ipPolicy
+	limiter  *requestLimiter
+	stats    *httpTunnelStats
+	pages    *errorPages
+	mocks    *mockRules
+	faults   *faultInjector
+	cors     *corsPolicy
+
+	maxRequestBody  int64
+	maxResponseBody int64
+	client          *http.Client
+	retry           upstreamRetry
+}
+
+// newHTTPTunnel prepares the runtime state of an HTTP tunnel
+func newHTTPTunnel(config model.TunnelConfig) (*httpTunnel, error) {
+	scheme := config.Scheme
+	switch scheme {
+	case "":
+		scheme = "http"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported upstream scheme: %s", scheme)
+	}
+
+	router, err := newHTTPRouter(config.Routes, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newUpstreamTLSConfig(config.UpstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+	if config.Protocol == model.UpstreamProtocolH2C && scheme != "http" {
+		return nil, fmt.Errorf("h2c requires an http upstream; HTTPS upstreams negotiate HTTP/2 automatically")
+	}
+	transport, retry, err := newUpstreamTransport(config.Timeouts, config.Protocol, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel := &httpTunnel{
+		config: config,
+		scheme: scheme,
+		router: router,
+		stats:  &httpTunnelStats{},
+		client: &http.Client{
+			Transport: transport,
+			// Redirects go back to the visitor so that their Location and
+			// cookies can be rewritten to the public origin
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		retry: retry,
+	}
+	if config.Upstream != "" {
+		tunnel.upstream = upstreamURL(config.Upstream, scheme)
+	}
+
+	tunnel.policy, err = newIPPolicy(config.IPPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.limiter, err = newRequestLimiter(config.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.webhook, err = newWebhookVerifier(config.Verify)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.pages, err = newErrorPages(config.ErrorPages)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.mocks, err = newMockRules(config.MockFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.faults, err = newFaultInjector(config.Faults)
+	if err != nil {
+		return nil, err
+	}
+
+	tunnel.cors, err = newCORSPolicy(config.CORS)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.MaxRequestBody < 0 || config.MaxResponseBody < 0 {
+		return nil, fmt.Errorf("body size limits must not be negative")
+	}
+	tunnel.maxRequestBody = config.MaxRequestBody
+	if tunnel.maxRequestBody == 0 {
+		tunnel.maxRequestBody = defaultMaxRequestBody
+	}
+	tunnel.maxResponseBody = config.MaxResponseBody
+	if tunnel.maxResponseBody == 0 {
+		tunnel.maxResponseBody = defaultMaxResponseBody
+	}
+
+	if config.Auth != nil {
+		switch config.Auth.Type {
+		case model.AuthTypeBasic, model.AuthTypeHeader:
+			err = validateAuth(config.Auth)
+		case model.AuthTypeOIDC:
+			tunnel.oidc, err = newOIDCAuthenticator(config.Auth)
+		case model.AuthTypeJWT:
+			tunnel.jwt, err = newJWTVerifier(config.Auth)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.Upstreams) > 0 {
+		tunnel.pool, err = newUpstreamPool(config.Upstreams, scheme, config.LoadBalance, config.HashHeader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tunnel, nil
+}
+
+// start starts the background tasks of the tunnel
+func (t *httpTunnel) start(logger port.Logger) {
+	if t.pool != nil {
+		t.pool.startHealthChecks(t.config.HealthCheck, t.client.Transport, logger)
+	}
+	t.mocks.start(logger)
+}
+
+// close stops the background tasks of the tunnel
+func (t *httpTunnel) close() {
+	if t.pool != nil {
+		t.pool.close()
+	}
+	t.policy.close()
+	t.mocks.close()
+	t.client.CloseIdleConnections()
+}
+
+// restricted reports whether the tunnel limits which requests reach the local service
+func (t *httpTunnel) restricted() bool {
+	config := t.config
+	return config.Auth != nil || config.IPPolicy != nil || config.RateLimit != nil || config.Verify != nil
+}
+
+// displayName returns the name shown to visitors on error pages
+func (t *httpTunnel) displayName(publicHost string) string {
+	switch {
+	case t.config.Name != "":
+		return t.config.Name
+	case t.config.Subdomain != "":
+		return t.config.Subdomain
+	default:
+		return publicHost
+	}
+}
+
+// statsSnapshot returns the request counters, rate limits and webhook
+// verification results of the tunnel
+func (t *httpTunnel) statsSnapshot() model.TunnelStats {
+	stats := t.stats.snapshot()
+	stats.RateLimit = t.config.RateLimit
+	if t.webhook != nil {
+		stats.Webhook = t.webhook.status()
+	}
+	return stats
+}
+
+// resolve returns the base URL of the local target and the request URI to
+// forward for a request URI received from the server. Requests that match
+// no route go to the upstream pool, the tunnel's upstream address, or the
+// local port the server asked for, in that order. The returned function
+// must be called once the request is done.
+func (t *httpTunnel) resolve(requestURI string, header http.Header, localPort int) (string, string, func(), error) {
+	path, query := requestURI, ""
+	if idx := strings.Index(requestURI, "?"); idx >= 0 {
+		path, query = requestURI[:idx], requestURI[idx:]
+	}
+
+	if target, forwardPath, ok := t.router.match(path); ok {
+		return target, forwardPath + query, func() {}, nil
+	}
+
+	if t.pool != nil {
+		u, release, err := t.pool.pick(header)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return u.baseURL, requestURI, release, nil
+	}
+
+	if t.upstream != "" {
+		return t.upstream, requestURI, func() {}, nil
+	}
+
+	if localPort <= 0 {
+		localPort = t.config.LocalPort
+	}
+	return fmt.Sprintf("%s://localhost:%d", t.scheme, localPort), requestURI, func() {}, nil
+}
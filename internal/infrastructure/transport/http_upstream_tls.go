@@ -0,0 +1,44 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// newUpstreamTLSConfig builds the TLS configuration used to connect to HTTPS
+// upstreams. A nil config verifies upstreams against the system roots.
+func newUpstreamTLSConfig(config *model.UpstreamTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	if config == nil {
+		return tlsConfig, nil
+	}
+
+	tlsConfig.InsecureSkipVerify = config.InsecureSkipVerify
+	tlsConfig.ServerName = config.ServerName
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upstream CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load upstream client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,268 @@
+package transport
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+const (
+	// defaultWebhookTolerance is the maximum age of signed webhook timestamps
+	defaultWebhookTolerance = 5 * time.Minute
+
+	// webhookVerifiedHeader tells the local service which provider signed a request
+	webhookVerifiedHeader = "X-Haxor-Webhook-Verified"
+)
+
+// webhookVerifier checks the HMAC signatures webhook providers add to their requests
+type webhookVerifier struct {
+	provider  model.WebhookProvider
+	secret    []byte
+	tolerance time.Duration
+	header    string
+	// now returns the current time to check signed timestamps against
+	now func() time.Time
+
+	mutex  sync.Mutex
+	result model.WebhookStatus
+}
+
+// newWebhookVerifier creates the verifier of a tunnel. It returns nil when
+// the tunnel has no webhook verification.
+func newWebhookVerifier(config *model.WebhookVerifyConfig) (*webhookVerifier, error) {
+	if config == nil {
+		return nil, nil
+	}
+	if config.Secret == "" {
+		return nil, fmt.Errorf("webhook verification requires a secret")
+	}
+
+	v := &webhookVerifier{
+		provider:  config.Provider,
+		secret:    []byte(config.Secret),
+		tolerance: config.Tolerance,
+		header:    config.Header,
+		now:       time.Now,
+		result:    model.WebhookStatus{Provider: config.Provider},
+	}
+	if v.tolerance <= 0 {
+		v.tolerance = defaultWebhookTolerance
+	}
+
+	switch config.Provider {
+	case model.WebhookProviderGitHub, model.WebhookProviderStripe, model.WebhookProviderSlack,
+		model.WebhookProviderTwilio, model.WebhookProviderShopify:
+	case model.WebhookProviderGeneric:
+		if v.header == "" {
+			return nil, fmt.Errorf("generic webhook verification requires a header")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported webhook provider: %s", config.Provider)
+	}
+
+	return v, nil
+}
+
+// verify checks the signature of a webhook request and records the result.
+// publicURL is the URL the provider sent the request to, which Twilio
+// includes in its signature.
+func (v *webhookVerifier) verify(request *model.HTTPRequest, publicURL string) error {
+	err := v.check(request, publicURL)
+
+	result := &model.WebhookResult{Method: request.Method, URL: request.URL, Verified: err == nil, Time: v.now()}
+	v.mutex.Lock()
+	if err != nil {
+		result.Error = err.Error()
+		v.result.Rejected++
+	} else {
+		v.result.Verified++
+	}
+	v.result.Last = result
+	v.mutex.Unlock()
+
+	return err
+}
+
+// status returns the verification counters and the latest result
+func (v *webhookVerifier) status() *model.WebhookStatus {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	status := v.result
+	if status.Last != nil {
+		last := *status.Last
+		status.Last = &last
+	}
+	return &status
+}
+
+// check checks the signature of a webhook request
+func (v *webhookVerifier) check(request *model.HTTPRequest, publicURL string) error {
+	header := request.Headers
+	body := request.Body
+
+	switch v.provider {
+	case model.WebhookProviderGitHub:
+		signature, ok := cutPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok {
+			return fmt.Errorf("missing X-Hub-Signature-256 header")
+		}
+		return compareHexMAC(v.mac(sha256.New, body), signature)
+
+	case model.WebhookProviderShopify:
+		return compareBase64MAC(v.mac(sha256.New, body), header.Get("X-Shopify-Hmac-Sha256"))
+
+	case model.WebhookProviderSlack:
+		timestamp := header.Get("X-Slack-Request-Timestamp")
+		if err := v.checkTimestamp(timestamp); err != nil {
+			return err
+		}
+		signature, ok := cutPrefix(header.Get("X-Slack-Signature"), "v0=")
+		if !ok {
+			return fmt.Errorf("missing X-Slack-Signature header")
+		}
+		return compareHexMAC(v.mac(sha256.New, []byte("v0:"+timestamp+":"), body), signature)
+
+	case model.WebhookProviderStripe:
+		return v.verifyStripe(header.Get("Stripe-Signature"), body)
+
+	case model.WebhookProviderTwilio:
+		return v.verifyTwilio(header, body, publicURL)
+
+	default:
+		signature := header.Get(v.header)
+		if idx := strings.Index(signature, "="); idx >= 0 {
+			signature = signature[idx+1:]
+		}
+		return compareHexMAC(v.mac(sha256.New, body), signature)
+	}
+}
+
+// verifyStripe checks a "t=...,v1=..." Stripe-Signature header. Any of the
+// v1 signatures may match, which happens while a secret is being rolled.
+func (v *webhookVerifier) verifyStripe(value string, body []byte) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = val
+		case "v1":
+			signatures = append(signatures, val)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("missing or malformed Stripe-Signature header")
+	}
+	if err := v.checkTimestamp(timestamp); err != nil {
+		return err
+	}
+
+	expected := v.mac(sha256.New, []byte(timestamp+"."), body)
+	for _, signature := range signatures {
+		if compareHexMAC(expected, signature) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature mismatch")
+}
+
+// verifyTwilio checks X-Twilio-Signature, the base64 HMAC-SHA1 of the URL
+// followed by the sorted form parameters. JSON bodies are signed through the
+// bodySHA256 query parameter instead.
+func (v *webhookVerifier) verifyTwilio(header http.Header, body []byte, publicURL string) error {
+	signature := header.Get("X-Twilio-Signature")
+	if signature == "" {
+		return fmt.Errorf("missing X-Twilio-Signature header")
+	}
+
+	payload := publicURL
+	if u, err := url.Parse(publicURL); err == nil && u.Query().Get("bodySHA256") != "" {
+		sum := sha256.Sum256(body)
+		if !hmac.Equal([]byte(hex.EncodeToString(sum[:])), []byte(u.Query().Get("bodySHA256"))) {
+			return fmt.Errorf("body hash mismatch")
+		}
+	} else if strings.HasPrefix(header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return fmt.Errorf("invalid form body: %v", err)
+		}
+		keys := make([]string, 0, len(form))
+		for key := range form {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			values := form[key]
+			sort.Strings(values)
+			for _, value := range values {
+				payload += key + value
+			}
+		}
+	}
+
+	return compareBase64MAC(v.mac(sha1.New, []byte(payload)), signature)
+}
+
+// checkTimestamp rejects signed Unix timestamps outside the tolerance window
+func (v *webhookVerifier) checkTimestamp(value string) error {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid signature timestamp")
+	}
+	age := v.now().Sub(time.Unix(seconds, 0))
+	if math.Abs(float64(age)) > float64(v.tolerance) {
+		return fmt.Errorf("signature timestamp outside tolerance of %s", v.tolerance)
+	}
+	return nil
+}
+
+// mac computes the HMAC of the concatenated parts with the secret
+func (v *webhookVerifier) mac(h func() hash.Hash, parts ...[]byte) []byte {
+	m := hmac.New(h, v.secret)
+	for _, part := range parts {
+		m.Write(part)
+	}
+	return m.Sum(nil)
+}
+
+// compareHexMAC compares a MAC with a hex encoded signature in constant time
+func compareHexMAC(expected []byte, signature string) error {
+	decoded, err := hex.DecodeString(strings.TrimSpace(signature))
+	if err != nil || !hmac.Equal(expected, decoded) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// compareBase64MAC compares a MAC with a base64 encoded signature in constant time
+func compareBase64MAC(expected []byte, signature string) error {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil || !hmac.Equal(expected, decoded) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
+
+// cutPrefix returns s without prefix and whether s had it
+func cutPrefix(s string, prefix string) (string, bool) {
+	if !strings.HasPrefix(s, prefix) {
+		return "", false
+	}
+	return s[len(prefix):], true
+}
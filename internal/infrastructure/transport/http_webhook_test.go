@@ -0,0 +1,309 @@
+package transport
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// Reference signatures published by the providers, or computed with
+// openssl for providers that publish none
+const (
+	// https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries
+	githubSecret    = "It's a Secret to Everybody"
+	githubBody      = "Hello, World!"
+	githubSignature = "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	// https://api.slack.com/authentication/verifying-requests-from-slack
+	slackSecret    = "8f742231b10e8888abcd99yyyzzz85a5"
+	slackTimestamp = "1531420618"
+	slackBody      = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+	slackSignature = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+
+	// https://www.twilio.com/docs/usage/webhooks/webhooks-security
+	twilioToken     = "12345"
+	twilioURL       = "https://mycompany.com/myapp.php?foo=1&bar=2"
+	twilioForm      = "To=%2B18005551212&From=%2B12349013030&Digits=1234&Caller=%2B12349013030&CallSid=CA1234567890ABCDE"
+	twilioSignature = "0/KCTR6DLpKmkAf8muzZqo1nDgQ="
+	// printf 'https://mycompany.com/twilio?bodySHA256=<sha256 of body>' | openssl dgst -sha1 -hmac 12345 -binary | base64
+	twilioJSONURL       = "https://mycompany.com/twilio?bodySHA256=2499358d3b73660dd38ee30338a37045ae22062c41f1be9df142285a615a6ae9"
+	twilioJSONBody      = `{"status":"delivered"}`
+	twilioJSONSignature = "9JFnDeaNLNdRPJWSOdC+2G5VfWU="
+
+	// printf '1700000000.<body>' | openssl dgst -sha256 -hmac whsec_test_secret
+	stripeSecret    = "whsec_test_secret"
+	stripeTimestamp = "1700000000"
+	stripeBody      = `{"id":"evt_test_webhook","object":"event"}`
+	stripeSignature = "d95c6b7477fbd7e9f90b1b0ef5f9c7ac25abca5382460e0d988c2b2a5b71b990"
+
+	// printf '<body>' | openssl dgst -sha256 -hmac shpss_test_secret -binary | base64
+	shopifySecret    = "shpss_test_secret"
+	shopifyBody      = `{"id":820982911946154508}`
+	shopifySignature = "AU5n5C4JV1EbE8g4yEzV+ZHbqkXpM29HXkzNAa5zicA="
+)
+
+func TestWebhookVerifierSignatures(t *testing.T) {
+	// unix returns the time of a Unix timestamp plus offset
+	unix := func(timestamp string, offset time.Duration) time.Time {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return time.Unix(seconds, 0).Add(offset)
+	}
+	form := "application/x-www-form-urlencoded"
+
+	tests := []struct {
+		name      string
+		config    model.WebhookVerifyConfig
+		now       time.Time
+		header    http.Header
+		body      string
+		publicURL string
+		valid     bool
+	}{
+		{
+			name:   "github",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGitHub, Secret: githubSecret},
+			header: http.Header{"X-Hub-Signature-256": {"sha256=" + githubSignature}},
+			body:   githubBody,
+			valid:  true,
+		},
+		{
+			name:   "github tampered body",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGitHub, Secret: githubSecret},
+			header: http.Header{"X-Hub-Signature-256": {"sha256=" + githubSignature}},
+			body:   githubBody + " ",
+		},
+		{
+			name:   "github wrong secret",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGitHub, Secret: "other"},
+			header: http.Header{"X-Hub-Signature-256": {"sha256=" + githubSignature}},
+			body:   githubBody,
+		},
+		{
+			name:   "github SHA-1 header only",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGitHub, Secret: githubSecret},
+			header: http.Header{"X-Hub-Signature": {"sha1=" + githubSignature}},
+			body:   githubBody,
+		},
+		{
+			name:   "slack",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderSlack, Secret: slackSecret},
+			now:    unix(slackTimestamp, time.Minute),
+			header: http.Header{"X-Slack-Request-Timestamp": {slackTimestamp}, "X-Slack-Signature": {slackSignature}},
+			body:   slackBody,
+			valid:  true,
+		},
+		{
+			name:   "slack replayed after the tolerance",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderSlack, Secret: slackSecret},
+			now:    unix(slackTimestamp, 6*time.Minute),
+			header: http.Header{"X-Slack-Request-Timestamp": {slackTimestamp}, "X-Slack-Signature": {slackSignature}},
+			body:   slackBody,
+		},
+		{
+			name:   "slack within a custom tolerance",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderSlack, Secret: slackSecret, Tolerance: time.Hour},
+			now:    unix(slackTimestamp, 30*time.Minute),
+			header: http.Header{"X-Slack-Request-Timestamp": {slackTimestamp}, "X-Slack-Signature": {slackSignature}},
+			body:   slackBody,
+			valid:  true,
+		},
+		{
+			name:   "slack timestamp in the future",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderSlack, Secret: slackSecret},
+			now:    unix(slackTimestamp, -6*time.Minute),
+			header: http.Header{"X-Slack-Request-Timestamp": {slackTimestamp}, "X-Slack-Signature": {slackSignature}},
+			body:   slackBody,
+		},
+		{
+			name:   "slack timestamp changed",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderSlack, Secret: slackSecret},
+			now:    unix(slackTimestamp, 0),
+			header: http.Header{"X-Slack-Request-Timestamp": {"1531420619"}, "X-Slack-Signature": {slackSignature}},
+			body:   slackBody,
+		},
+		{
+			name:   "stripe",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderStripe, Secret: stripeSecret},
+			now:    unix(stripeTimestamp, time.Minute),
+			header: http.Header{"Stripe-Signature": {"t=" + stripeTimestamp + ",v1=" + stripeSignature}},
+			body:   stripeBody,
+			valid:  true,
+		},
+		{
+			name:   "stripe with a rolled secret",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderStripe, Secret: stripeSecret},
+			now:    unix(stripeTimestamp, 0),
+			header: http.Header{"Stripe-Signature": {"t=" + stripeTimestamp + ",v1=" + githubSignature + ",v1=" + stripeSignature + ",v0=00"}},
+			body:   stripeBody,
+			valid:  true,
+		},
+		{
+			name:   "stripe without a matching v1 signature",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderStripe, Secret: stripeSecret},
+			now:    unix(stripeTimestamp, 0),
+			header: http.Header{"Stripe-Signature": {"t=" + stripeTimestamp + ",v1=" + githubSignature + ",v0=" + stripeSignature}},
+			body:   stripeBody,
+		},
+		{
+			name:   "stripe replayed after the tolerance",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderStripe, Secret: stripeSecret},
+			now:    unix(stripeTimestamp, 10*time.Minute),
+			header: http.Header{"Stripe-Signature": {"t=" + stripeTimestamp + ",v1=" + stripeSignature}},
+			body:   stripeBody,
+		},
+		{
+			name:   "stripe tampered body",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderStripe, Secret: stripeSecret},
+			now:    unix(stripeTimestamp, 0),
+			header: http.Header{"Stripe-Signature": {"t=" + stripeTimestamp + ",v1=" + stripeSignature}},
+			body:   `{"id":"evt_test_webhook","object":"charge"}`,
+		},
+		{
+			name:   "stripe without timestamp",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderStripe, Secret: stripeSecret},
+			now:    unix(stripeTimestamp, 0),
+			header: http.Header{"Stripe-Signature": {"v1=" + stripeSignature}},
+			body:   stripeBody,
+		},
+		{
+			name:      "twilio form parameters in any order",
+			config:    model.WebhookVerifyConfig{Provider: model.WebhookProviderTwilio, Secret: twilioToken},
+			header:    http.Header{"X-Twilio-Signature": {twilioSignature}, "Content-Type": {form}},
+			body:      twilioForm,
+			publicURL: twilioURL,
+			valid:     true,
+		},
+		{
+			name:      "twilio tampered parameter",
+			config:    model.WebhookVerifyConfig{Provider: model.WebhookProviderTwilio, Secret: twilioToken},
+			header:    http.Header{"X-Twilio-Signature": {twilioSignature}, "Content-Type": {form}},
+			body:      twilioForm + "0",
+			publicURL: twilioURL,
+		},
+		{
+			name:      "twilio other URL",
+			config:    model.WebhookVerifyConfig{Provider: model.WebhookProviderTwilio, Secret: twilioToken},
+			header:    http.Header{"X-Twilio-Signature": {twilioSignature}, "Content-Type": {form}},
+			body:      twilioForm,
+			publicURL: "https://mycompany.com/myapp.php?bar=2&foo=1",
+		},
+		{
+			name:      "twilio JSON body",
+			config:    model.WebhookVerifyConfig{Provider: model.WebhookProviderTwilio, Secret: twilioToken},
+			header:    http.Header{"X-Twilio-Signature": {twilioJSONSignature}, "Content-Type": {"application/json"}},
+			body:      twilioJSONBody,
+			publicURL: twilioJSONURL,
+			valid:     true,
+		},
+		{
+			name:      "twilio tampered JSON body",
+			config:    model.WebhookVerifyConfig{Provider: model.WebhookProviderTwilio, Secret: twilioToken},
+			header:    http.Header{"X-Twilio-Signature": {twilioJSONSignature}, "Content-Type": {"application/json"}},
+			body:      `{"status":"failed"}`,
+			publicURL: twilioJSONURL,
+		},
+		{
+			name:   "shopify",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderShopify, Secret: shopifySecret},
+			header: http.Header{"X-Shopify-Hmac-Sha256": {shopifySignature}},
+			body:   shopifyBody,
+			valid:  true,
+		},
+		{
+			name:   "shopify tampered body",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderShopify, Secret: shopifySecret},
+			header: http.Header{"X-Shopify-Hmac-Sha256": {shopifySignature}},
+			body:   `{"id":820982911946154509}`,
+		},
+		{
+			name:   "generic with prefix",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGeneric, Secret: githubSecret, Header: "X-Signature"},
+			header: http.Header{"X-Signature": {"sha256=" + githubSignature}},
+			body:   githubBody,
+			valid:  true,
+		},
+		{
+			name:   "generic without prefix",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGeneric, Secret: githubSecret, Header: "X-Signature"},
+			header: http.Header{"X-Signature": {githubSignature}},
+			body:   githubBody,
+			valid:  true,
+		},
+		{
+			name:   "generic missing header",
+			config: model.WebhookVerifyConfig{Provider: model.WebhookProviderGeneric, Secret: githubSecret, Header: "X-Signature"},
+			header: http.Header{},
+			body:   githubBody,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			v, err := newWebhookVerifier(&config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.now.IsZero() {
+				v.now = func() time.Time { return tt.now }
+			}
+
+			request := &model.HTTPRequest{Method: http.MethodPost, URL: "/hook", Headers: tt.header, Body: []byte(tt.body)}
+			err = v.verify(request, tt.publicURL)
+			if (err == nil) != tt.valid {
+				t.Errorf("verify() error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifierRecordsResults(t *testing.T) {
+	v, err := newWebhookVerifier(&model.WebhookVerifyConfig{Provider: model.WebhookProviderGitHub, Secret: githubSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	v.now = func() time.Time { return now }
+
+	if status := v.status(); status.Provider != model.WebhookProviderGitHub || status.Last != nil {
+		t.Errorf("status before any request = %+v", status)
+	}
+
+	signed := &model.HTTPRequest{Method: http.MethodPost, URL: "/hook", Headers: http.Header{"X-Hub-Signature-256": {"sha256=" + githubSignature}}, Body: []byte(githubBody)}
+	forged := &model.HTTPRequest{Method: http.MethodPost, URL: "/forged", Headers: http.Header{}, Body: []byte(githubBody)}
+	v.verify(signed, "")
+	v.verify(signed, "")
+	v.verify(forged, "")
+
+	status := v.status()
+	if status.Verified != 2 || status.Rejected != 1 {
+		t.Errorf("verified, rejected = %d, %d; want 2, 1", status.Verified, status.Rejected)
+	}
+	want := model.WebhookResult{Method: http.MethodPost, URL: "/forged", Error: "missing X-Hub-Signature-256 header", Time: now}
+	if status.Last == nil || *status.Last != want {
+		t.Errorf("last result = %+v, want %+v", status.Last, want)
+	}
+}
+
+func TestNewWebhookVerifierRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config model.WebhookVerifyConfig
+	}{
+		{"no secret", model.WebhookVerifyConfig{Provider: model.WebhookProviderGitHub}},
+		{"generic without header", model.WebhookVerifyConfig{Provider: model.WebhookProviderGeneric, Secret: "s"}},
+		{"unknown provider", model.WebhookVerifyConfig{Provider: "gitlab", Secret: "s"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := newWebhookVerifier(&tt.config); err == nil {
+			t.Errorf("%s: newWebhookVerifier succeeded, want error", tt.name)
+		}
+	}
+}
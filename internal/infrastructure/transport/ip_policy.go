@@ -0,0 +1,167 @@
+package transport
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ipPolicy decides which visitor addresses may reach a tunnel
+type ipPolicy struct {
+	allow          []*net.IPNet
+	deny           []*net.IPNet
+	allowCountries map[string]bool
+	denyCountries  map[string]bool
+	geoip          *maxminddb.Reader
+}
+
+// newIPPolicy builds the policy of a tunnel. It returns nil when the tunnel
+// has no IP policy; a nil policy allows every address.
+func newIPPolicy(config *model.IPPolicyConfig) (*ipPolicy, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	allow, err := parseCIDRs(config.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parseCIDRs(config.Deny)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &ipPolicy{
+		allow:          allow,
+		deny:           deny,
+		allowCountries: countrySet(config.AllowCountries),
+		denyCountries:  countrySet(config.DenyCountries),
+	}
+
+	if len(policy.allowCountries) > 0 || len(policy.denyCountries) > 0 {
+		if config.GeoIPDatabase == "" {
+			return nil, fmt.Errorf("country rules require a GeoIP database")
+		}
+		policy.geoip, err = maxminddb.Open(config.GeoIPDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open GeoIP database: %v", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// check returns an error describing why a visitor address is rejected, or
+// nil when it may reach the tunnel. Deny rules win over allow rules.
+func (p *ipPolicy) check(remoteAddr string) error {
+	if p == nil {
+		return nil
+	}
+
+	ip := remoteIP(remoteAddr)
+	if ip == nil {
+		return fmt.Errorf("unknown visitor address %q", remoteAddr)
+	}
+
+	if containsIP(p.deny, ip) {
+		return fmt.Errorf("%s is in a denied range", ip)
+	}
+	country := p.country(ip)
+	if country != "" && p.denyCountries[country] {
+		return fmt.Errorf("%s is in denied country %s", ip, country)
+	}
+
+	if len(p.allow) == 0 && len(p.allowCountries) == 0 {
+		return nil
+	}
+	if containsIP(p.allow, ip) || (country != "" && p.allowCountries[country]) {
+		return nil
+	}
+	if country != "" {
+		return fmt.Errorf("%s (%s) is not in an allowed range or country", ip, country)
+	}
+	return fmt.Errorf("%s is not in an allowed range", ip)
+}
+
+// country returns the ISO country code of an address, or an empty string
+// when there is no GeoIP database or the address is not in it
+func (p *ipPolicy) country(ip net.IP) string {
+	if p.geoip == nil {
+		return ""
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := p.geoip.Lookup(ip, &record); err != nil {
+		return ""
+	}
+	return strings.ToUpper(record.Country.ISOCode)
+}
+
+// close releases the GeoIP database
+func (p *ipPolicy) close() {
+	if p != nil && p.geoip != nil {
+		p.geoip.Close()
+	}
+}
+
+// parseCIDRs parses CIDR ranges; single IPs are treated as /32 or /128
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", value)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range: %s", value)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// containsIP reports whether any of the ranges contains ip
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// countrySet builds an upper case lookup set of country codes
+func countrySet(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[strings.ToUpper(strings.TrimSpace(code))] = true
+	}
+	return set
+}
+
+// remoteIP parses the IP of a visitor address given as "ip" or "ip:port"
+func remoteIP(addr string) net.IP {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(strings.Trim(addr, "[]"))
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+func TestIPPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *model.IPPolicyConfig
+		addr    string
+		allowed bool
+	}{
+		{"no policy", nil, "198.51.100.1:80", true},
+		{"no rules", &model.IPPolicyConfig{}, "198.51.100.1", true},
+		{"allowed range", &model.IPPolicyConfig{Allow: []string{"10.8.0.0/16"}}, "10.8.1.2:5000", true},
+		{"outside allowed range", &model.IPPolicyConfig{Allow: []string{"10.8.0.0/16"}}, "10.9.1.2:5000", false},
+		{"single allowed IP", &model.IPPolicyConfig{Allow: []string{"198.51.100.7"}}, "198.51.100.7", true},
+		{"deny wins over allow", &model.IPPolicyConfig{Allow: []string{"10.8.0.0/16"}, Deny: []string{"10.8.5.0/24"}}, "10.8.5.9:22", false},
+		{"deny only", &model.IPPolicyConfig{Deny: []string{"10.8.5.0/24"}}, "10.8.6.9:22", true},
+		{"IPv6 range", &model.IPPolicyConfig{Allow: []string{"2001:db8::/32"}}, "[2001:db8::1]:443", true},
+		{"IPv6 outside range", &model.IPPolicyConfig{Allow: []string{"2001:db8::/32"}}, "[2001:db9::1]:443", false},
+		{"IPv4-mapped IPv6", &model.IPPolicyConfig{Allow: []string{"10.8.0.0/16"}}, "[::ffff:10.8.1.2]:22", true},
+		{"unknown address", &model.IPPolicyConfig{Deny: []string{"10.8.5.0/24"}}, "", false},
+		{"invalid address", &model.IPPolicyConfig{}, "not-an-ip:22", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newIPPolicy(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := policy.check(tt.addr); (err == nil) != tt.allowed {
+				t.Errorf("check(%q) = %v, allowed %v", tt.addr, err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestNewIPPolicyRejectsInvalidRules(t *testing.T) {
+	for _, config := range []*model.IPPolicyConfig{
+		{Allow: []string{"10.8.0.0/33"}},
+		{Deny: []string{"not-an-ip"}},
+		{AllowCountries: []string{"ID"}},
+	} {
+		if _, err := newIPPolicy(config); err == nil {
+			t.Errorf("newIPPolicy(%+v) succeeded, want error", config)
+		}
+	}
+}
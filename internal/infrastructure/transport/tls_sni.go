@@ -0,0 +1,155 @@
+package transport
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	// tlsRecordHeaderLen is the length of a TLS record header
+	tlsRecordHeaderLen = 5
+	// maxClientHelloLen is the largest ClientHello read before giving up
+	maxClientHelloLen = tlsRecordHeaderLen + 16384
+)
+
+var (
+	// errNotClientHello is returned for connections that do not start with a TLS ClientHello
+	errNotClientHello = errors.New("connection does not start with a TLS ClientHello")
+	// errIncompleteClientHello is returned while more data is needed to read the ClientHello
+	errIncompleteClientHello = errors.New("incomplete TLS ClientHello")
+	// errClientHelloTooLarge is returned when a ClientHello does not end within maxClientHelloLen bytes
+	errClientHelloTooLarge = errors.New("TLS ClientHello is too large")
+)
+
+// clientHelloServerName returns the SNI hostname of the TLS ClientHello at
+// the start of data. It returns errIncompleteClientHello when data ends
+// before the ClientHello does, and an empty name when the ClientHello has
+// no SNI extension.
+func clientHelloServerName(data []byte) (string, error) {
+	if len(data) < tlsRecordHeaderLen {
+		return "", errIncompleteClientHello
+	}
+	// Handshake record (22) of TLS 1.0 or later
+	if data[0] != 22 || data[1] != 3 {
+		return "", errNotClientHello
+	}
+	recordLen := int(binary.BigEndian.Uint16(data[3:5]))
+	if len(data) < tlsRecordHeaderLen+recordLen {
+		return "", errIncompleteClientHello
+	}
+	hello := data[tlsRecordHeaderLen : tlsRecordHeaderLen+recordLen]
+
+	// Handshake header: type ClientHello (1) and a 24-bit length
+	if len(hello) < 4 || hello[0] != 1 {
+		return "", errNotClientHello
+	}
+	helloLen := int(hello[1])<<16 | int(hello[2])<<8 | int(hello[3])
+	if helloLen > len(hello)-4 {
+		// ClientHellos that span several records are not supported
+		return "", errNotClientHello
+	}
+	r := tlsReader(hello[4 : 4+helloLen])
+
+	// Skip version, random, session ID, cipher suites and compression methods
+	if !r.skip(2+32) || !r.skipVector(1) || !r.skipVector(2) || !r.skipVector(1) {
+		return "", errNotClientHello
+	}
+	if len(r) == 0 {
+		return "", nil
+	}
+	extensions, ok := r.vector(2)
+	if !ok {
+		return "", errNotClientHello
+	}
+	for len(extensions) > 0 {
+		extType, ok1 := extensions.uint16()
+		extData, ok2 := extensions.vector(2)
+		if !ok1 || !ok2 {
+			return "", errNotClientHello
+		}
+		// server_name extension (0) with a list of names
+		if extType != 0 {
+			continue
+		}
+		names, ok := extData.vector(2)
+		if !ok {
+			return "", errNotClientHello
+		}
+		for len(names) > 0 {
+			nameType, ok1 := names.uint8()
+			name, ok2 := names.vector(2)
+			if !ok1 || !ok2 {
+				return "", errNotClientHello
+			}
+			if nameType == 0 {
+				return strings.ToLower(string(name)), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// tlsReader reads the fields of a TLS handshake message
+type tlsReader []byte
+
+func (r *tlsReader) skip(n int) bool {
+	if len(*r) < n {
+		return false
+	}
+	*r = (*r)[n:]
+	return true
+}
+
+func (r *tlsReader) uint8() (uint8, bool) {
+	if len(*r) < 1 {
+		return 0, false
+	}
+	v := (*r)[0]
+	*r = (*r)[1:]
+	return v, true
+}
+
+func (r *tlsReader) uint16() (uint16, bool) {
+	if len(*r) < 2 {
+		return 0, false
+	}
+	v := binary.BigEndian.Uint16(*r)
+	*r = (*r)[2:]
+	return v, true
+}
+
+// vector reads a field prefixed with a lenBytes long length
+func (r *tlsReader) vector(lenBytes int) (tlsReader, bool) {
+	if len(*r) < lenBytes {
+		return nil, false
+	}
+	n := 0
+	for _, b := range (*r)[:lenBytes] {
+		n = n<<8 | int(b)
+	}
+	*r = (*r)[lenBytes:]
+	if len(*r) < n {
+		return nil, false
+	}
+	v := (*r)[:n]
+	*r = (*r)[n:]
+	return v, true
+}
+
+func (r *tlsReader) skipVector(lenBytes int) bool {
+	_, ok := r.vector(lenBytes)
+	return ok
+}
+
+// matchServerName reports whether an SNI hostname matches the hostname of a
+// tunnel; "*.example.com" matches one label below example.com
+func matchServerName(hostname string, serverName string) bool {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if strings.HasPrefix(hostname, "*.") {
+		i := strings.IndexByte(serverName, '.')
+		return i > 0 && serverName[i+1:] == hostname[2:]
+	}
+	return hostname == serverName
+}
@@ -0,0 +1,136 @@
+package transport
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// clientHello returns the first TLS record crypto/tls sends for serverName
+func clientHello(t *testing.T, serverName string) []byte {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		tls.Client(clientConn, &tls.Config{ServerName: serverName, InsecureSkipVerify: true}).Handshake()
+		clientConn.Close()
+	}()
+
+	serverConn.SetDeadline(time.Now().Add(5 * time.Second))
+	header := make([]byte, tlsRecordHeaderLen)
+	if _, err := io.ReadFull(serverConn, header); err != nil {
+		t.Fatal(err)
+	}
+	record := make([]byte, binary.BigEndian.Uint16(header[3:5]))
+	if _, err := io.ReadFull(serverConn, record); err != nil {
+		t.Fatal(err)
+	}
+	return append(header, record...)
+}
+
+func TestClientHelloServerName(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverName string
+		want       string
+	}{
+		{"hostname", "api.example.com", "api.example.com"},
+		{"upper case hostname", "API.Example.com", "api.example.com"},
+		{"no SNI for IP addresses", "127.0.0.1", ""},
+		{"no server name", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hello := clientHello(t, tt.serverName)
+
+			got, err := clientHelloServerName(hello)
+			if err != nil || got != tt.want {
+				t.Fatalf("clientHelloServerName() = %q, %v; want %q", got, err, tt.want)
+			}
+
+			// Data after the ClientHello does not matter
+			if got, err := clientHelloServerName(append(hello, 0x17, 0x03, 0x03)); err != nil || got != tt.want {
+				t.Errorf("with trailing data = %q, %v; want %q", got, err, tt.want)
+			}
+
+			// Every prefix asks for more data
+			for n := 0; n < len(hello); n++ {
+				if _, err := clientHelloServerName(hello[:n]); err != errIncompleteClientHello {
+					t.Fatalf("prefix of %d bytes: error = %v, want %v", n, err, errIncompleteClientHello)
+				}
+			}
+		})
+	}
+}
+
+func TestClientHelloServerNameRejectsOtherData(t *testing.T) {
+	hello := clientHello(t, "api.example.com")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"plain HTTP", []byte("GET / HTTP/1.1\r\nHost: api.example.com\r\n\r\n")},
+		{"SSLv2 version", append([]byte{22, 2}, hello[2:]...)},
+		{"application data record", append([]byte{23}, hello[1:]...)},
+		{"ServerHello", func() []byte {
+			data := append([]byte(nil), hello...)
+			data[tlsRecordHeaderLen] = 2
+			return data
+		}()},
+		{"handshake longer than record", func() []byte {
+			data := append([]byte(nil), hello...)
+			data[tlsRecordHeaderLen+1] = 0xff
+			return data
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := clientHelloServerName(tt.data); err != errNotClientHello {
+				t.Errorf("error = %v, want %v", err, errNotClientHello)
+			}
+		})
+	}
+}
+
+func TestClientHelloServerNameCorruptedBytes(t *testing.T) {
+	hello := clientHello(t, "api.example.com")
+
+	// Corrupted ClientHellos must be rejected or parsed, never crash the parser
+	for i := tlsRecordHeaderLen; i < len(hello); i++ {
+		for _, b := range []byte{0x00, 0xff} {
+			data := append([]byte(nil), hello...)
+			data[i] = b
+			clientHelloServerName(data)
+		}
+	}
+}
+
+func TestMatchServerName(t *testing.T) {
+	tests := []struct {
+		hostname   string
+		serverName string
+		want       bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "API.example.com.", true},
+		{"api.example.com", "web.example.com", false},
+		{"api.example.com", "", false},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "a.b.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", ".example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchServerName(tt.hostname, tt.serverName); got != tt.want {
+			t.Errorf("matchServerName(%q, %q) = %v, want %v", tt.hostname, tt.serverName, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+)
+
+const (
+	// certReloadInterval is how often certificate files are checked for changes
+	certReloadInterval = time.Second
+	// terminatedReadHeaderTimeout limits how long visitors may take to send
+	// the headers of a request to a terminating TLS tunnel
+	terminatedReadHeaderTimeout = 30 * time.Second
+)
+
+// tlsTerminator terminates the TLS connections of a TLS tunnel with the
+// client's own certificates and proxies the decrypted requests to the local
+// HTTP service. The connections of the server are handed to an HTTP server
+// through in-memory pipes, so the relay only ever sees encrypted traffic.
+type tlsTerminator struct {
+	certs     *certificateStore
+	listener  *pipeListener
+	server    *http.Server
+	tlsConfig *tls.Config
+}
+
+// unsupportedTLSSettings returns the settings of a tunnel that only the HTTP
+// pipeline of HTTP tunnels enforces. TLS tunnels reject them, so that a
+// setting such as auth never silently leaves a tunnel open.
+func unsupportedTLSSettings(config model.TunnelConfig) []string {
+	var settings []string
+	add := func(set bool, name string) {
+		if set {
+			settings = append(settings, name)
+		}
+	}
+	add(config.Auth != nil, "auth")
+	add(config.IPPolicy != nil, "ip_policy")
+	add(config.RateLimit != nil, "rate_limit")
+	add(config.Verify != nil, "verify")
+	add(config.CORS != nil, "cors")
+	add(config.MockFile != "", "mock_file")
+	add(config.MaxRequestBody != 0, "max_request_body")
+	add(config.MaxResponseBody != 0, "max_response_body")
+	add(len(config.Routes) > 0, "routes")
+	add(len(config.Upstreams) > 0, "upstreams")
+	add(config.RewriteURLs, "rewrite_urls")
+	add(config.RewriteHeaders, "rewrite_headers")
+	// Error pages are rendered for requests decrypted by the client
+	add(len(config.ErrorPages) > 0 && config.TLSTermination == nil, "error_pages")
+	return settings
+}
+
+// newTLSTerminator prepares TLS termination for a TLS tunnel. It returns nil
+// when the tunnel forwards its connections without decrypting them.
+func newTLSTerminator(config model.TunnelConfig, logger port.Logger) (*tlsTerminator, error) {
+	if config.TLSTermination == nil {
+		return nil, nil
+	}
+
+	certs, err := newCertificateStore(config.TLSTermination.Certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme := config.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("unsupported upstream scheme: %s", scheme)
+	}
+	target := config.Upstream
+	if target == "" {
+		host := config.LocalAddr
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		target = net.JoinHostPort(host, strconv.Itoa(config.LocalPort))
+	}
+	pages, err := newErrorPages(config.ErrorPages)
+	if err != nil {
+		return nil, err
+	}
+	tunnelName := config.Name
+	if tunnelName == "" {
+		tunnelName = config.Hostname
+	}
+
+	upstream, err := url.Parse(upstreamURL(target, scheme))
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %s: %v", target, err)
+	}
+	if config.Protocol == model.UpstreamProtocolH2C && upstream.Scheme != "http" {
+		return nil, fmt.Errorf("h2c requires an http upstream")
+	}
+
+	upstreamTLS, err := newUpstreamTLSConfig(config.UpstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+	transport, _, err := newUpstreamTransport(config.Timeouts, config.Protocol, upstreamTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = upstream.Scheme
+			req.URL.Host = upstream.Host
+			req.URL.Path = strings.TrimRight(upstream.Path, "/") + req.URL.Path
+			req.Header.Set("X-Forwarded-Host", req.Host)
+			req.Header.Set("X-Forwarded-Proto", "https")
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			logger.Error("Failed to forward request %s %s to local service: %v", req.Method, req.URL.Path, err)
+			status := upstreamErrorStatus(err)
+			body, contentType := pages.render(newErrorPageData(status, tunnelName, "", ""), req.Header.Get("Accept"))
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(status)
+			w.Write(body)
+		},
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+
+	listener := newPipeListener()
+	return &tlsTerminator{
+		certs:    certs,
+		listener: listener,
+		server: &http.Server{
+			Handler:           proxy,
+			ReadHeaderTimeout: terminatedReadHeaderTimeout,
+			ErrorLog:          log.New(io.Discard, "", 0),
+		},
+		tlsConfig: &tls.Config{
+			GetCertificate: certs.get,
+			NextProtos:     []string{"h2", "http/1.1"},
+			MinVersion:     tls.VersionTLS12,
+		},
+	}, nil
+}
+
+// start serves the connections handed to the terminator and watches the
+// certificate files for changes
+func (t *tlsTerminator) start(logger port.Logger) {
+	if t == nil {
+		return
+	}
+	t.certs.start(logger)
+	go t.server.Serve(tls.NewListener(t.listener, t.tlsConfig))
+}
+
+// accept hands a connection of the server to the terminator and returns the
+// end of its pipe that carries the encrypted traffic
+func (t *tlsTerminator) accept() (net.Conn, error) {
+	local, remote := net.Pipe()
+	if err := t.listener.push(remote); err != nil {
+		local.Close()
+		remote.Close()
+		return nil, err
+	}
+	return local, nil
+}
+
+// close stops the terminator and closes its connections
+func (t *tlsTerminator) close() {
+	if t == nil {
+		return
+	}
+	t.server.Close()
+	t.listener.Close()
+	t.certs.close()
+}
+
+// pipeListener is a net.Listener for connections handed over in memory
+type pipeListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// push hands a connection to the listener
+func (l *pipeListener) push(conn net.Conn) error {
+	select {
+	case l.conns <- conn:
+		return nil
+	case <-l.done:
+		return net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// pipeAddr is the address of in-memory connections
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "pipe" }
+
+// certificateStore holds the certificates of a terminating TLS tunnel and
+// reloads them whenever one of their files changes
+type certificateStore struct {
+	files []model.TLSCertificateFiles
+
+	mutex   sync.RWMutex
+	certs   []*tls.Certificate
+	version string
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// newCertificateStore loads the certificates of a terminating TLS tunnel
+func newCertificateStore(files []model.TLSCertificateFiles) (*certificateStore, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("TLS termination requires at least one certificate")
+	}
+	for _, file := range files {
+		if file.CertFile == "" || file.KeyFile == "" {
+			return nil, fmt.Errorf("TLS termination certificates require a certificate file and a key file")
+		}
+	}
+
+	s := &certificateStore{
+		files:  files,
+		stopCh: make(chan struct{}),
+	}
+	if _, err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// start watches the certificate files for changes
+func (s *certificateStore) start(logger port.Logger) {
+	go func() {
+		ticker := time.NewTicker(certReloadInterval)
+		defer ticker.Stop()
+
+		// Log a failing file once instead of every second until it is fixed
+		var lastErr string
+		for {
+			select {
+			case <-ticker.C:
+				changed, err := s.reload()
+				switch {
+				case err != nil:
+					if err.Error() != lastErr {
+						logger.Warn("Failed to reload TLS certificates, keeping the previous ones: %v", err)
+						lastErr = err.Error()
+					}
+				case changed:
+					logger.Info("Reloaded %d TLS certificates", len(s.files))
+					lastErr = ""
+				}
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// close stops watching the certificate files
+func (s *certificateStore) close() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// reload loads the certificates when one of their files changed since they
+// were last loaded. Certificates that are renewed in place are only loaded
+// once both files can be parsed as a pair.
+func (s *certificateStore) reload() (bool, error) {
+	var version strings.Builder
+	for _, file := range s.files {
+		for _, name := range []string{file.CertFile, file.KeyFile} {
+			info, err := os.Stat(name)
+			if err != nil {
+				return false, fmt.Errorf("failed to read certificate: %v", err)
+			}
+			fmt.Fprintf(&version, "%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+
+	s.mutex.RLock()
+	unchanged := version.String() == s.version
+	s.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	certs := make([]*tls.Certificate, 0, len(s.files))
+	for _, file := range s.files {
+		cert, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			return false, fmt.Errorf("invalid certificate %s: %v", file.CertFile, err)
+		}
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return false, fmt.Errorf("invalid certificate %s: %v", file.CertFile, err)
+		}
+		certs = append(certs, &cert)
+	}
+
+	s.mutex.Lock()
+	s.certs = certs
+	s.version = version.String()
+	s.mutex.Unlock()
+	return true, nil
+}
+
+// get returns the certificate for the SNI hostname of a visitor: the first
+// certificate valid for the hostname, or the first certificate otherwise
+func (s *certificateStore) get(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if hello.ServerName != "" {
+		for _, cert := range s.certs {
+			if cert.Leaf.VerifyHostname(hello.ServerName) == nil {
+				return cert, nil
+			}
+		}
+	}
+	return s.certs[0], nil
+}
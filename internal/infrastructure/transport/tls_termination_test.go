@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/logger"
+)
+
+// writeTestCertificate writes a self-signed certificate for host and its key
+// to certFile and keyFile
+func writeTestCertificate(t *testing.T, certFile string, keyFile string, host string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertificateStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCertificate(t, certFile, keyFile, "app.example.com")
+
+	store, err := newCertificateStore([]model.TLSCertificateFiles{{CertFile: certFile, KeyFile: keyFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello := &tls.ClientHelloInfo{ServerName: "app.example.com"}
+	first, _ := store.get(hello)
+
+	if changed, err := store.reload(); changed || err != nil {
+		t.Errorf("reload of unchanged files = %v, %v; want false, nil", changed, err)
+	}
+
+	writeTestCertificate(t, certFile, keyFile, "app.example.com")
+	// Renewals are detected by modification time and size; make sure the
+	// rewrite is visible on file systems with coarse timestamps too
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	os.Chtimes(keyFile, later, later)
+	if changed, err := store.reload(); !changed || err != nil {
+		t.Fatalf("reload after renewal = %v, %v; want true, nil", changed, err)
+	}
+	renewed, _ := store.get(hello)
+	if renewed.Leaf.SerialNumber.Cmp(first.Leaf.SerialNumber) == 0 {
+		t.Fatal("get returned the old certificate after the renewal")
+	}
+
+	// A broken renewal keeps the certificate in use
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.reload(); err == nil {
+		t.Error("reload accepted an invalid key")
+	}
+	if current, _ := store.get(hello); current != renewed {
+		t.Error("get did not keep the last valid certificate")
+	}
+}
+
+func TestCertificateStoreGetBySNI(t *testing.T) {
+	dir := t.TempDir()
+	var files []model.TLSCertificateFiles
+	for _, host := range []string{"www.example.com", "*.api.example.com"} {
+		name := strings.ReplaceAll(host, "*", "wildcard")
+		file := model.TLSCertificateFiles{CertFile: filepath.Join(dir, name+".pem"), KeyFile: filepath.Join(dir, name+"-key.pem")}
+		writeTestCertificate(t, file.CertFile, file.KeyFile, host)
+		files = append(files, file)
+	}
+
+	store, err := newCertificateStore(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{"www.example.com", "www.example.com"},
+		{"v1.api.example.com", "*.api.example.com"},
+		{"other.example.com", "www.example.com"},
+		{"", "www.example.com"},
+	}
+	for _, tt := range tests {
+		cert, err := store.get(&tls.ClientHelloInfo{ServerName: tt.serverName})
+		if err != nil || cert.Leaf.DNSNames[0] != tt.want {
+			t.Errorf("get(%q) = %v, %v; want %s", tt.serverName, cert.Leaf.DNSNames, err, tt.want)
+		}
+	}
+}
+
+func TestUnsupportedTLSSettings(t *testing.T) {
+	termination := &model.TLSTerminationConfig{}
+	tests := []struct {
+		name   string
+		config model.TunnelConfig
+		want   []string
+	}{
+		{"passthrough", model.TunnelConfig{Hostname: "app.example.com"}, nil},
+		{"auth", model.TunnelConfig{Auth: &model.TunnelAuth{Type: model.AuthTypeBasic}}, []string{"auth"}},
+		{"policies", model.TunnelConfig{IPPolicy: &model.IPPolicyConfig{}, RateLimit: &model.RateLimitConfig{}, CORS: &model.CORSConfig{}}, []string{"ip_policy", "rate_limit", "cors"}},
+		{"body limit", model.TunnelConfig{MaxRequestBody: 1024}, []string{"max_request_body"}},
+		{"error pages with termination", model.TunnelConfig{TLSTermination: termination, ErrorPages: map[string]string{"502": "502.html"}}, nil},
+		{"error pages without termination", model.TunnelConfig{ErrorPages: map[string]string{"502": "502.html"}}, []string{"error_pages"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unsupportedTLSSettings(tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unsupportedTLSSettings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTLSTerminatorErrorPage(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCertificate(t, certFile, keyFile, "app.example.com")
+
+	// A port nobody listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	log := logger.NewLogger(io.Discard, "error")
+	terminator, err := newTLSTerminator(model.TunnelConfig{
+		Name:           "site",
+		Type:           model.TunnelTypeTLS,
+		Hostname:       "app.example.com",
+		LocalAddr:      "127.0.0.1",
+		LocalPort:      port,
+		TLSTermination: &model.TLSTerminationConfig{Certificates: []model.TLSCertificateFiles{{CertFile: certFile, KeyFile: keyFile}}},
+	}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminator.start(log)
+	defer terminator.close()
+
+	conn, err := terminator.accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: "app.example.com", InsecureSkipVerify: true, NextProtos: []string{"http/1.1"}})
+	defer tlsConn.Close()
+
+	if _, err := io.WriteString(tlsConn, "GET / HTTP/1.1\r\nHost: app.example.com\r\nAccept: text/html\r\nConnection: close\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(tlsConn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || !strings.Contains(string(body), "<code>site</code>") {
+		t.Errorf("error page = %s %q, want the branded page of tunnel site", resp.Header.Get("Content-Type"), body)
+	}
+}
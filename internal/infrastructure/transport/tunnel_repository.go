@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,10 @@ type TunnelRepository struct {
 	logger      port.Logger
 	tunnels     map[string]*model.Tunnel
 	connections map[string]net.Conn
+	udpTunnels  map[string]*udpTunnel
+	policies    map[string]*ipPolicy
+	hellos      map[string][]byte
+	terminators map[string]*tlsTerminator
 	mutex       sync.RWMutex
 }
 
@@ -27,11 +33,18 @@ func NewTunnelRepository(client *Client, logger port.Logger) *TunnelRepository {
 		logger:      logger,
 		tunnels:     make(map[string]*model.Tunnel),
 		connections: make(map[string]net.Conn),
+		udpTunnels:  make(map[string]*udpTunnel),
+		policies:    make(map[string]*ipPolicy),
+		hellos:      make(map[string][]byte),
+		terminators: make(map[string]*tlsTerminator),
 		mutex:       sync.RWMutex{},
 	}
 
 
 	client.RegisterHandler(model.MessageTypeData, repo.handleDataMessage)
+	client.RegisterHandler(model.MessageTypeClose, repo.handleCloseMessage)
+	client.RegisterHandler(model.MessageTypeDatagram, repo.handleDatagramMessage)
+	client.RegisterHandler(model.MessageTypeDatagramClose, repo.handleDatagramCloseMessage)
 
 	return repo
 }
@@ -46,13 +59,71 @@ func (r *TunnelRepository) Register(config model.TunnelConfig) (*model.Tunnel, e
 	}
 
 
+	// Hostnames outside the base domain of the server are custom domains
+	if config.Hostname != "" {
+		if config.Type != model.TunnelTypeHTTP && config.Type != model.TunnelTypeTLS {
+			return nil, fmt.Errorf("hostnames are only supported by HTTP and TLS tunnels")
+		}
+		if model.IsCustomDomain(config.Hostname, r.client.BaseDomain()) {
+			if err := r.client.CheckCustomDomains(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Validate the IP policy before the tunnel is registered
+	var policy *ipPolicy
+	if config.Type == model.TunnelTypeTCP || config.Type == model.TunnelTypeUDP {
+		var err error
+		if policy, err = newIPPolicy(config.IPPolicy); err != nil {
+			return nil, fmt.Errorf("invalid IP policy: %v", err)
+		}
+	}
+
+	// TLS tunnels are routed by the SNI hostname of their connections
+	if config.Type == model.TunnelTypeTLS && config.Hostname == "" {
+		policy.close()
+		return nil, fmt.Errorf("TLS tunnels require a hostname")
+	}
+	if config.TLSTermination != nil && config.Type != model.TunnelTypeTLS {
+		policy.close()
+		return nil, fmt.Errorf("TLS termination is only supported by TLS tunnels")
+	}
+	if config.Type == model.TunnelTypeTLS {
+		if settings := unsupportedTLSSettings(config); len(settings) > 0 {
+			policy.close()
+			return nil, fmt.Errorf("TLS tunnels do not support %s; use an HTTP tunnel for them", strings.Join(settings, ", "))
+		}
+	}
+
+	// Terminating TLS tunnels decrypt their connections with their own certificates
+	terminator, err := newTLSTerminator(config, r.logger)
+	if err != nil {
+		policy.close()
+		return nil, fmt.Errorf("invalid TLS termination configuration: %v", err)
+	}
+
+	// UDP tunnels open a local socket for each remote peer
+	var udp *udpTunnel
+	if config.Type == model.TunnelTypeUDP {
+		var err error
+		if udp, err = newUDPTunnel(config, policy, r.client, r.logger); err != nil {
+			policy.close()
+			return nil, fmt.Errorf("invalid UDP tunnel configuration: %v", err)
+		}
+	}
+
 	response, err := r.client.SendRegisterTunnel(config)
 	if err != nil {
+		policy.close()
+		terminator.close()
 		return nil, fmt.Errorf("failed to register tunnel: %v", err)
 	}
 
 
 	if !response.Success {
+		policy.close()
+		terminator.close()
 		return nil, fmt.Errorf("tunnel registration failed: %s", response.Error)
 	}
 
@@ -62,19 +133,38 @@ func (r *TunnelRepository) Register(config model.TunnelConfig) (*model.Tunnel, e
 
 	if config.Type == model.TunnelTypeHTTP {
 		tunnel.SetHTTPInfo(response.URL)
-	} else if config.Type == model.TunnelTypeTCP {
+	} else if config.Type == model.TunnelTypeTCP || config.Type == model.TunnelTypeUDP {
+		tunnel.SetTCPInfo(response.RemotePort)
+	} else if config.Type == model.TunnelTypeTLS {
 		tunnel.SetTCPInfo(response.RemotePort)
+		tunnel.URL = response.URL
 	}
 
 
+
 	r.mutex.Lock()
 	r.tunnels[response.TunnelID] = tunnel
+	if udp != nil {
+		udp.id = response.TunnelID
+		r.udpTunnels[response.TunnelID] = udp
+	}
+	if terminator != nil {
+		r.terminators[response.TunnelID] = terminator
+	}
+	if policy != nil && config.Type == model.TunnelTypeTCP {
+		r.policies[response.TunnelID] = policy
+	}
 	r.mutex.Unlock()
 
+	terminator.start(r.logger)
+
 
 
 
-	if config.Type == model.TunnelTypeTCP {
+	// Unix socket upstreams are dialed when the server opens a connection.
+	// So are local ports of tunnels with an IP policy, since connections
+	// accepted by the local listener carry no visitor address to check.
+	if config.Type == model.TunnelTypeTCP && model.UnixSocketPath(config.Upstream) == "" && policy == nil {
 		go r.startTunnelListener(tunnel)
 	}
 
@@ -98,8 +188,21 @@ func (r *TunnelRepository) Unregister(tunnelID string) error {
 
 	r.mutex.Lock()
 	delete(r.tunnels, tunnelID)
+	udp := r.udpTunnels[tunnelID]
+	delete(r.udpTunnels, tunnelID)
+	terminator := r.terminators[tunnelID]
+	delete(r.terminators, tunnelID)
+	policy := r.policies[tunnelID]
+	delete(r.policies, tunnelID)
 	r.mutex.Unlock()
 
+	policy.close()
+
+	if udp != nil {
+		udp.close()
+	}
+	terminator.close()
+
 	return nil
 }
 
@@ -138,8 +241,26 @@ func (r *TunnelRepository) SendData(tunnelID string, connectionID string, data [
 func (r *TunnelRepository) HandleData(tunnelID string, connectionID string, data []byte) error {
 	r.mutex.RLock()
 	conn, exists := r.connections[connectionID]
+	tunnel := r.tunnels[tunnelID]
 	r.mutex.RUnlock()
 
+	if !exists && tunnel != nil && tunnel.Config.Type == model.TunnelTypeTLS {
+		return r.acceptTLSConnection(tunnel, connectionID, data)
+	}
+
+	// Connections opened by the server are dialed to the local service
+	if !exists && tunnel != nil && tunnel.Config.Type == model.TunnelTypeTCP {
+		network, address := "tcp", net.JoinHostPort(tunnel.Config.LocalAddr, strconv.Itoa(tunnel.Config.LocalPort))
+		if path := model.UnixSocketPath(tunnel.Config.Upstream); path != "" {
+			network, address = "unix", path
+		}
+		var err error
+		if conn, err = r.dialLocal(tunnel, connectionID, network, address); err != nil {
+			return r.rejectConnection(tunnel, connectionID, err)
+		}
+		exists = true
+	}
+
 	if !exists {
 		return fmt.Errorf("connection with ID %s not found", connectionID)
 	}
@@ -163,9 +284,74 @@ func (r *TunnelRepository) handleDataMessage(msg *model.Message) error {
 	}
 
 
+	// Visitors are checked against the IP policy of the tunnel when their
+	// connection starts; rejected connections are closed on the server
+	r.mutex.RLock()
+	_, exists := r.connections[payload.ConnectionID]
+	policy := r.policies[payload.TunnelID]
+	r.mutex.RUnlock()
+	if !exists && policy != nil {
+		if err := policy.check(payload.RemoteAddr); err != nil {
+			r.logger.Warn("Rejected connection %s for tunnel %s by IP policy: %v", payload.ConnectionID, payload.TunnelID, err)
+			return r.client.SendClose(payload.TunnelID, payload.ConnectionID)
+		}
+	}
+
 	return r.HandleData(payload.TunnelID, payload.ConnectionID, payload.Data)
 }
 
+// handleCloseMessage closes the local connection of a connection ended by the server
+func (r *TunnelRepository) handleCloseMessage(msg *model.Message) error {
+	var payload model.ClosePayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		return fmt.Errorf("failed to parse close payload: %v", err)
+	}
+
+	r.mutex.Lock()
+	conn := r.connections[payload.ConnectionID]
+	delete(r.hellos, payload.ConnectionID)
+	r.mutex.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+	return nil
+}
+
+// handleDatagramMessage forwards a datagram of a remote peer to the local
+// service of a UDP tunnel
+func (r *TunnelRepository) handleDatagramMessage(msg *model.Message) error {
+	var payload model.DatagramPayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		return fmt.Errorf("failed to parse datagram payload: %v", err)
+	}
+
+	r.mutex.RLock()
+	udp, exists := r.udpTunnels[payload.TunnelID]
+	r.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("UDP tunnel with ID %s not found", payload.TunnelID)
+	}
+
+	return udp.handleDatagram(payload)
+}
+
+// handleDatagramCloseMessage closes a UDP session ended by the server
+func (r *TunnelRepository) handleDatagramCloseMessage(msg *model.Message) error {
+	var payload model.DatagramClosePayload
+	if err := msg.ParsePayload(&payload); err != nil {
+		return fmt.Errorf("failed to parse datagram close payload: %v", err)
+	}
+
+	r.mutex.RLock()
+	udp, exists := r.udpTunnels[payload.TunnelID]
+	r.mutex.RUnlock()
+	if exists {
+		udp.closeSession(payload.SessionID)
+	}
+	return nil
+}
+
 // startTunnelListener starts a listener for a tunnel.
 func (r *TunnelRepository) startTunnelListener(tunnel *model.Tunnel) {
 	localAddr := fmt.Sprintf("%s:%d", tunnel.Config.LocalAddr, tunnel.Config.LocalPort)
@@ -195,6 +381,95 @@ func (r *TunnelRepository) startTunnelListener(tunnel *model.Tunnel) {
 	}
 }
 
+// dialLocal opens a connection to the local service of a tunnel for a
+// connection opened by the server.
+func (r *TunnelRepository) dialLocal(tunnel *model.Tunnel, connectionID string, network string, address string) (net.Conn, error) {
+	r.logger.Info("Opening connection %s to %s for tunnel %s", connectionID, address, tunnel.ID)
+
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		r.logger.Error("Failed to connect to %s: %v", address, err)
+		return nil, err
+	}
+
+	r.trackConnection(tunnel, connectionID, conn)
+
+	return conn, nil
+}
+
+// trackConnection routes the data of a connection opened by the server to
+// conn and forwards the data read from conn to the server
+func (r *TunnelRepository) trackConnection(tunnel *model.Tunnel, connectionID string, conn net.Conn) {
+	r.mutex.Lock()
+	r.connections[connectionID] = conn
+	r.mutex.Unlock()
+
+	go r.handleConnection(tunnel.ID, connectionID, conn)
+}
+
+// acceptTLSConnection reads the TLS ClientHello of a connection opened by the
+// server for a TLS passthrough tunnel. Once its SNI hostname is known to
+// match the tunnel, the connection is forwarded to the local TLS server
+// without being decrypted, or decrypted by the client when the tunnel
+// terminates TLS itself. Connections that cannot be accepted are closed on
+// the server.
+func (r *TunnelRepository) acceptTLSConnection(tunnel *model.Tunnel, connectionID string, data []byte) error {
+	r.mutex.Lock()
+	hello := append(r.hellos[connectionID], data...)
+	delete(r.hellos, connectionID)
+	r.mutex.Unlock()
+
+	serverName, err := clientHelloServerName(hello)
+	if err == errIncompleteClientHello {
+		if len(hello) > maxClientHelloLen {
+			return r.rejectConnection(tunnel, connectionID, errClientHelloTooLarge)
+		}
+		r.mutex.Lock()
+		r.hellos[connectionID] = hello
+		r.mutex.Unlock()
+		return nil
+	}
+	if err != nil {
+		return r.rejectConnection(tunnel, connectionID, err)
+	}
+	if !matchServerName(tunnel.Config.Hostname, serverName) {
+		return r.rejectConnection(tunnel, connectionID, fmt.Errorf("SNI hostname %q does not match %s", serverName, tunnel.Config.Hostname))
+	}
+
+	r.mutex.RLock()
+	terminator := r.terminators[tunnel.ID]
+	r.mutex.RUnlock()
+
+	var conn net.Conn
+	if terminator != nil {
+		if conn, err = terminator.accept(); err != nil {
+			return r.rejectConnection(tunnel, connectionID, err)
+		}
+		r.logger.Info("Terminating TLS of connection %s for tunnel %s", connectionID, tunnel.ID)
+		r.trackConnection(tunnel, connectionID, conn)
+	} else {
+		address := net.JoinHostPort(tunnel.Config.LocalAddr, strconv.Itoa(tunnel.Config.LocalPort))
+		if conn, err = r.dialLocal(tunnel, connectionID, "tcp", address); err != nil {
+			return r.rejectConnection(tunnel, connectionID, err)
+		}
+	}
+
+	if _, err := conn.Write(hello); err != nil {
+		r.logger.Error("Failed to send data to local connection: %v", err)
+		return err
+	}
+	return nil
+}
+
+// rejectConnection asks the server to close a connection that was not
+// accepted and returns the reason
+func (r *TunnelRepository) rejectConnection(tunnel *model.Tunnel, connectionID string, reason error) error {
+	if err := r.client.SendClose(tunnel.ID, connectionID); err != nil {
+		r.logger.Debug("Failed to close rejected connection %s: %v", connectionID, err)
+	}
+	return fmt.Errorf("rejected connection %s for tunnel %s: %v", connectionID, tunnel.ID, reason)
+}
+
 // handleConnection handles a connection to a tunnel.
 func (r *TunnelRepository) handleConnection(tunnelID string, connectionID string, conn net.Conn) {
 	defer func() {
@@ -205,6 +480,10 @@ func (r *TunnelRepository) handleConnection(tunnelID string, connectionID string
 		r.logger.Info("Closing connection %s for tunnel %s", connectionID, tunnelID)
 	}()
 
+	// Faults are injected into the data sent from the local side to the server
+	faults := r.client.tunnelFaults(tunnelID)
+	faulty := faults.affectsConnection()
+
 	buffer := make([]byte, 4096)
 	for {
 		n, err := conn.Read(buffer)
@@ -215,6 +494,15 @@ func (r *TunnelRepository) handleConnection(tunnelID string, connectionID string
 			break
 		}
 
+		if faulty {
+			delay, drop := faults.chunk(n)
+			if drop {
+				r.logger.Info("[FAULT] Dropping connection %s for tunnel %s", connectionID, tunnelID)
+				break
+			}
+			time.Sleep(delay)
+		}
+
 		if err := r.SendData(tunnelID, connectionID, buffer[:n]); err != nil {
 			r.logger.Debug("Sending data to server: %d bytes", n)
 			r.logger.Error("Failed to send data to server: %v", err)
@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+func TestTCPIPPolicyChecksVisitorAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen func(t *testing.T) (net.Listener, model.TunnelConfig)
+	}{
+		{"unix socket", func(t *testing.T) (net.Listener, model.TunnelConfig) {
+			path := filepath.Join(t.TempDir(), "app.sock")
+			listener, err := net.Listen("unix", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return listener, model.TunnelConfig{Upstream: "unix://" + path}
+		}},
+		{"local port", func(t *testing.T) (net.Listener, model.TunnelConfig) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			return listener, model.TunnelConfig{LocalAddr: "127.0.0.1", LocalPort: listener.Addr().(*net.TCPAddr).Port}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, config := tt.listen(t)
+			defer listener.Close()
+			testTCPIPPolicy(t, listener, config)
+		})
+	}
+}
+
+// testTCPIPPolicy checks that a TCP tunnel forwarding to listener only
+// accepts connections of visitors allowed by its IP policy
+func testTCPIPPolicy(t *testing.T, listener net.Listener, config model.TunnelConfig) {
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+
+	config.Type = model.TunnelTypeTCP
+	config.IPPolicy = &model.IPPolicyConfig{Allow: []string{"203.0.113.0/24"}}
+	tunnel, err := repo.Register(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Visitors outside the allowed range and visitors without an address are rejected
+	for _, rejected := range []model.DataPayload{
+		{TunnelID: tunnel.ID, ConnectionID: "denied", Data: []byte("x"), RemoteAddr: "198.51.100.1:5000"},
+		{TunnelID: tunnel.ID, ConnectionID: "unknown", Data: []byte("x")},
+	} {
+		server.send(model.MessageTypeData, rejected)
+		var closed model.ClosePayload
+		server.expect(model.MessageTypeClose, &closed)
+		if closed.ConnectionID != rejected.ConnectionID || closed.TunnelID != tunnel.ID {
+			t.Errorf("close = %+v, want connection %s", closed, rejected.ConnectionID)
+		}
+	}
+
+	server.send(model.MessageTypeData, model.DataPayload{TunnelID: tunnel.ID, ConnectionID: "allowed", Data: []byte("ping\n"), RemoteAddr: "203.0.113.9:5000"})
+
+	deadline := listener.(interface{ SetDeadline(time.Time) error })
+	deadline.SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Later data of an allowed connection carries no address
+	server.send(model.MessageTypeData, model.DataPayload{TunnelID: tunnel.ID, ConnectionID: "allowed", Data: []byte("again\n")})
+
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"ping\n", "again\n"} {
+		if line, err := reader.ReadString('\n'); err != nil || line != want {
+			t.Fatalf("local service read %q, %v; want %q", line, err, want)
+		}
+	}
+
+	conn.Write([]byte("pong"))
+	var reply model.DataPayload
+	server.expect(model.MessageTypeData, &reply)
+	if reply.ConnectionID != "allowed" || string(reply.Data) != "pong" {
+		t.Errorf("reply = %s %q, want allowed \"pong\"", reply.ConnectionID, reply.Data)
+	}
+
+	// A close message from the server ends the local connection
+	server.send(model.MessageTypeClose, model.ClosePayload{TunnelID: tunnel.ID, ConnectionID: "allowed"})
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("local connection read error = %v, want EOF", err)
+	}
+
+	// No other connection reached the local service
+	deadline.SetDeadline(time.Now().Add(100 * time.Millisecond))
+	if extra, err := listener.Accept(); err == nil {
+		extra.Close()
+		t.Error("a rejected visitor reached the local service")
+	}
+}
+
+func TestTLSConnectionsAreRoutedBySNI(t *testing.T) {
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	tunnel, err := repo.Register(model.TunnelConfig{
+		Type:      model.TunnelTypeTLS,
+		Hostname:  "api.example.com",
+		LocalAddr: "127.0.0.1",
+		LocalPort: listener.Addr().(*net.TCPAddr).Port,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A record header announcing a ClientHello longer than the buffering limit
+	oversized := append([]byte{22, 3, 1, 0xff, 0xff}, make([]byte, maxClientHelloLen)...)
+
+	tests := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{"other SNI hostname", [][]byte{clientHello(t, "web.example.com")}},
+		{"not TLS", [][]byte{[]byte("GET / HTTP/1.1\r\n\r\n")}},
+		{"oversized ClientHello", [][]byte{oversized[:maxClientHelloLen/2], oversized[maxClientHelloLen/2:]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, chunk := range tt.chunks {
+				server.send(model.MessageTypeData, model.DataPayload{TunnelID: tunnel.ID, ConnectionID: tt.name, Data: chunk})
+			}
+			var closed model.ClosePayload
+			server.expect(model.MessageTypeClose, &closed)
+			if closed.ConnectionID != tt.name {
+				t.Errorf("closed connection %q, want %q", closed.ConnectionID, tt.name)
+			}
+
+			repo.mutex.RLock()
+			buffered := len(repo.hellos)
+			repo.mutex.RUnlock()
+			if buffered != 0 {
+				t.Errorf("%d ClientHellos still buffered after the rejection", buffered)
+			}
+		})
+	}
+
+	// A matching ClientHello split over two messages reaches the local TLS server unchanged
+	hello := clientHello(t, "api.example.com")
+	server.send(model.MessageTypeData, model.DataPayload{TunnelID: tunnel.ID, ConnectionID: "ok", Data: hello[:10]})
+	server.send(model.MessageTypeData, model.DataPayload{TunnelID: tunnel.ID, ConnectionID: "ok", Data: hello[10:]})
+
+	listener.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	received := make([]byte, len(hello))
+	if _, err := io.ReadFull(conn, received); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(received, hello) {
+		t.Error("local TLS server received a different ClientHello")
+	}
+}
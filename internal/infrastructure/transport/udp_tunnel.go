@@ -0,0 +1,225 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+)
+
+const (
+	// defaultUDPIdleTimeout is how long an idle UDP session is kept by default
+	defaultUDPIdleTimeout = 60 * time.Second
+	// defaultMaxUDPSessions is the default number of concurrent UDP sessions
+	defaultMaxUDPSessions = 1024
+	// maxDatagramSize is the largest UDP payload
+	maxDatagramSize = 65535
+)
+
+// udpTunnel forwards the datagrams of a UDP tunnel to the local service.
+// Every remote peer gets a session with its own local socket, so that the
+// replies of the local service reach the peer that sent the request.
+type udpTunnel struct {
+	id          string
+	target      string
+	idleTimeout time.Duration
+	maxSessions int
+	policy      *ipPolicy
+	client      *Client
+	logger      port.Logger
+
+	sessions map[string]*udpSession
+	closed   bool
+	mutex    sync.Mutex
+}
+
+// udpSession is the local socket of one remote peer
+type udpSession struct {
+	id         string
+	conn       *net.UDPConn
+	lastActive int64 // unix nanoseconds, updated atomically
+}
+
+// newUDPTunnel prepares the runtime state of a UDP tunnel. The tunnel ID is
+// set once the server has registered the tunnel.
+func newUDPTunnel(config model.TunnelConfig, policy *ipPolicy, client *Client, logger port.Logger) (*udpTunnel, error) {
+	var sessions model.UDPConfig
+	if config.UDP != nil {
+		sessions = *config.UDP
+	}
+	if sessions.IdleTimeout < 0 || sessions.MaxSessions < 0 {
+		return nil, fmt.Errorf("UDP idle timeout and max sessions must not be negative")
+	}
+
+	host := config.LocalAddr
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	return &udpTunnel{
+		target:      net.JoinHostPort(host, strconv.Itoa(config.LocalPort)),
+		idleTimeout: durationOr(sessions.IdleTimeout, defaultUDPIdleTimeout),
+		maxSessions: intOr(sessions.MaxSessions, defaultMaxUDPSessions),
+		policy:      policy,
+		client:      client,
+		logger:      logger,
+		sessions:    make(map[string]*udpSession),
+	}, nil
+}
+
+// handleDatagram forwards a datagram of a remote peer to the local service,
+// opening a session for the peer on its first datagram
+func (t *udpTunnel) handleDatagram(payload model.DatagramPayload) error {
+	if payload.RemoteAddr != "" {
+		if err := t.policy.check(payload.RemoteAddr); err != nil {
+			t.logger.Warn("Rejected datagram for tunnel %s by IP policy: %v", t.id, err)
+			return nil
+		}
+	}
+
+	session, err := t.session(payload.SessionID)
+	if err != nil {
+		return err
+	}
+
+	atomic.StoreInt64(&session.lastActive, time.Now().UnixNano())
+	if _, err := session.conn.Write(payload.Data); err != nil {
+		t.logger.Error("Failed to send datagram to local service %s: %v", t.target, err)
+		return err
+	}
+	return nil
+}
+
+// session returns the session of a remote peer, opening it when needed
+func (t *udpTunnel) session(id string) (*udpSession, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if session, ok := t.sessions[id]; ok {
+		return session, nil
+	}
+	if t.closed {
+		return nil, fmt.Errorf("tunnel %s is closed", t.id)
+	}
+	if len(t.sessions) >= t.maxSessions {
+		return nil, fmt.Errorf("tunnel %s has reached its limit of %d UDP sessions", t.id, t.maxSessions)
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", t.target)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		t.logger.Error("Failed to open UDP socket to %s: %v", t.target, err)
+		return nil, err
+	}
+
+	session := &udpSession{id: id, conn: conn, lastActive: time.Now().UnixNano()}
+	t.sessions[id] = session
+	t.logger.Info("Opening UDP session %s for tunnel %s", id, t.id)
+
+	go t.readSession(session)
+
+	return session, nil
+}
+
+// readSession sends the replies of the local service to the remote peer
+// until the session has been idle for the tunnel's idle timeout
+func (t *udpTunnel) readSession(session *udpSession) {
+	defer t.endSession(session, true)
+
+	buffer := make([]byte, maxDatagramSize)
+	for {
+		session.conn.SetReadDeadline(time.Now().Add(t.idleTimeout))
+		n, err := session.conn.Read(buffer)
+		if err != nil {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				// Datagrams from the peer also keep the session alive
+				idle := time.Since(time.Unix(0, atomic.LoadInt64(&session.lastActive)))
+				if idle < t.idleTimeout {
+					continue
+				}
+				t.logger.Info("UDP session %s for tunnel %s expired after %s without datagrams", session.id, t.id, t.idleTimeout)
+			case errors.Is(err, syscall.ECONNREFUSED):
+				// The local service is not listening (yet); keep the session for its restart
+				t.logger.Debug("Local service %s refused a datagram of session %s", t.target, session.id)
+				continue
+			case !errors.Is(err, net.ErrClosed):
+				t.logger.Error("Failed to read datagram from local service: %v", err)
+			}
+			return
+		}
+
+		atomic.StoreInt64(&session.lastActive, time.Now().UnixNano())
+		if err := t.client.SendDatagram(t.id, session.id, buffer[:n]); err != nil {
+			t.logger.Error("Failed to send datagram to server: %v", err)
+			return
+		}
+	}
+}
+
+// closeSession closes the session of a remote peer that the server ended
+func (t *udpTunnel) closeSession(id string) {
+	t.mutex.Lock()
+	session := t.sessions[id]
+	t.mutex.Unlock()
+
+	if session != nil {
+		t.endSession(session, false)
+	}
+}
+
+// endSession closes a session. The server is told about it when notify is
+// true, that is when the client ended the session.
+func (t *udpTunnel) endSession(session *udpSession, notify bool) {
+	t.mutex.Lock()
+	current := t.sessions[session.id] == session
+	if current {
+		delete(t.sessions, session.id)
+	}
+	t.mutex.Unlock()
+
+	if !current {
+		return
+	}
+	session.conn.Close()
+	t.logger.Info("Closing UDP session %s for tunnel %s", session.id, t.id)
+
+	if notify {
+		if err := t.client.SendDatagramClose(t.id, session.id); err != nil {
+			t.logger.Debug("Failed to report closed UDP session %s: %v", session.id, err)
+		}
+	}
+}
+
+// close closes all sessions of the tunnel
+func (t *udpTunnel) close() {
+	t.mutex.Lock()
+	t.closed = true
+	sessions := t.sessions
+	t.sessions = make(map[string]*udpSession)
+	t.mutex.Unlock()
+
+	for _, session := range sessions {
+		session.conn.Close()
+	}
+	t.policy.close()
+}
+
+// intOr returns n, or fallback when n is not set
+func intOr(n int, fallback int) int {
+	if n > 0 {
+		return n
+	}
+	return fallback
+}
@@ -0,0 +1,101 @@
+package transport
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+)
+
+// startUDPEcho starts a local UDP service that answers every datagram with
+// "echo:" and the datagram, and reports the address of each sender
+func startUDPEcho(t *testing.T) (*net.UDPConn, chan string) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	senders := make(chan string, 16)
+	go func() {
+		buffer := make([]byte, maxDatagramSize)
+		for {
+			n, addr, err := conn.ReadFromUDP(buffer)
+			if err != nil {
+				return
+			}
+			senders <- addr.String()
+			conn.WriteToUDP(append([]byte("echo:"), buffer[:n]...), addr)
+		}
+	}()
+	return conn, senders
+}
+
+func TestUDPTunnelDatagramExchange(t *testing.T) {
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	echo, senders := startUDPEcho(t)
+
+	tunnel, err := repo.Register(model.TunnelConfig{
+		Type:      model.TunnelTypeUDP,
+		LocalAddr: "127.0.0.1",
+		LocalPort: echo.LocalAddr().(*net.UDPAddr).Port,
+		UDP:       &model.UDPConfig{IdleTimeout: 300 * time.Millisecond},
+		IPPolicy:  &model.IPPolicyConfig{Deny: []string{"198.51.100.0/24"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exchange := func(sessionID string, data string) string {
+		t.Helper()
+		server.send(model.MessageTypeDatagram, model.DatagramPayload{TunnelID: tunnel.ID, SessionID: sessionID, RemoteAddr: "203.0.113.5:51820", Data: []byte(data)})
+		var reply model.DatagramPayload
+		server.expect(model.MessageTypeDatagram, &reply)
+		if reply.SessionID != sessionID || string(reply.Data) != "echo:"+data {
+			t.Fatalf("reply = %s %q, want %s %q", reply.SessionID, reply.Data, sessionID, "echo:"+data)
+		}
+		return <-senders
+	}
+
+	// Datagrams of a session share a local socket
+	first := exchange("peer-1", "ping")
+	if again := exchange("peer-1", "ping2"); again != first {
+		t.Errorf("second datagram of peer-1 came from %s, want %s", again, first)
+	}
+	if other := exchange("peer-2", "hello"); other == first {
+		t.Errorf("peer-2 shares the local socket %s of peer-1", first)
+	}
+
+	// Datagrams rejected by the IP policy never reach the local service
+	server.send(model.MessageTypeDatagram, model.DatagramPayload{TunnelID: tunnel.ID, SessionID: "peer-3", RemoteAddr: "198.51.100.9:4000", Data: []byte("blocked")})
+	exchange("peer-2", "after")
+
+	// A session closed by the server gets a new local socket
+	server.send(model.MessageTypeDatagramClose, model.DatagramClosePayload{TunnelID: tunnel.ID, SessionID: "peer-1"})
+	if reopened := exchange("peer-1", "back"); reopened == first {
+		t.Errorf("peer-1 reused local socket %s after the server closed its session", first)
+	}
+
+	// Idle sessions are closed by the client and reported to the server
+	closed := map[string]bool{}
+	for len(closed) < 2 {
+		var payload model.DatagramClosePayload
+		server.expect(model.MessageTypeDatagramClose, &payload)
+		if payload.TunnelID != tunnel.ID {
+			t.Errorf("datagram_close for tunnel %s, want %s", payload.TunnelID, tunnel.ID)
+		}
+		closed[payload.SessionID] = true
+	}
+	if !closed["peer-1"] || !closed["peer-2"] {
+		t.Errorf("closed sessions = %v, want peer-1 and peer-2", closed)
+	}
+	select {
+	case sender := <-senders:
+		t.Errorf("unexpected datagram from %s reached the local service", sender)
+	default:
+	}
+}
@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+)
+
+// unixSocketHostSuffix marks URL hosts that encode a Unix socket path
+const unixSocketHostSuffix = ".sock"
+
+// upstreamDialer is the dialer used for connections to local services
+var upstreamDialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+}
+
+// unixSocketHost encodes a Unix socket path as a URL host so that socket
+// upstreams can be used as base URLs by the HTTP transport
+func unixSocketHost(path string) string {
+	return hex.EncodeToString([]byte(path)) + unixSocketHostSuffix
+}
+
+// unixSocketFromHost returns the socket path encoded in a URL host (with or
+// without port) created by unixSocketHost
+func unixSocketFromHost(hostport string) (string, bool) {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	if !strings.HasSuffix(host, unixSocketHostSuffix) {
+		return "", false
+	}
+	path, err := hex.DecodeString(strings.TrimSuffix(host, unixSocketHostSuffix))
+	if err != nil {
+		return "", false
+	}
+	return string(path), true
+}
+
+// dialUpstream dials a local service with the default dialer
+var dialUpstream = upstreamDialContext(upstreamDialer)
+
+// upstreamDialContext returns a dial function that dials local services with
+// dialer, connecting to the Unix socket encoded in addr when there is one
+func upstreamDialContext(dialer *net.Dialer) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		if path, ok := unixSocketFromHost(addr); ok {
+			return dialer.DialContext(ctx, "unix", path)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
+
+// upstreamDisplayHost returns the host to show to the local service for a
+// URL host, replacing encoded Unix socket paths with "localhost"
+func upstreamDisplayHost(host string) string {
+	if _, ok := unixSocketFromHost(host); ok {
+		return "localhost"
+	}
+	return host
+}
@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/port"
+	"golang.org/x/net/http2"
+)
+
+const (
+	defaultDialTimeout           = 10 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultResponseHeaderTimeout = 60 * time.Second
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultRetryBackoff          = 250 * time.Millisecond
+
+	// maxIdleConnsPerUpstream is the number of keep-alive connections kept
+	// open to each local service; the net/http default of 2 is too low for
+	// pages that load many assets at once
+	maxIdleConnsPerUpstream = 32
+)
+
+// upstreamRetry is how often and how patiently refused requests are retried
+type upstreamRetry struct {
+	retries int
+	backoff time.Duration
+}
+
+// newUpstreamTransport creates the transport shared by all requests of a
+// tunnel, so that keep-alive connections to the local service are reused
+func newUpstreamTransport(config *model.UpstreamTimeoutConfig, protocol model.UpstreamProtocol, tlsConfig *tls.Config) (http.RoundTripper, upstreamRetry, error) {
+	var timeouts model.UpstreamTimeoutConfig
+	if config != nil {
+		timeouts = *config
+	}
+	if timeouts.Dial < 0 || timeouts.TLSHandshake < 0 || timeouts.ResponseHeader < 0 || timeouts.Idle < 0 ||
+		timeouts.Retries < 0 || timeouts.RetryBackoff < 0 {
+		return nil, upstreamRetry{}, fmt.Errorf("upstream timeouts and retries must not be negative")
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   durationOr(timeouts.Dial, defaultDialTimeout),
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = upstreamDialContext(dialer)
+	transport.TLSClientConfig = tlsConfig
+	transport.TLSHandshakeTimeout = durationOr(timeouts.TLSHandshake, defaultTLSHandshakeTimeout)
+	transport.ResponseHeaderTimeout = durationOr(timeouts.ResponseHeader, defaultResponseHeaderTimeout)
+	transport.IdleConnTimeout = durationOr(timeouts.Idle, defaultIdleConnTimeout)
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerUpstream
+
+	retry := upstreamRetry{
+		retries: timeouts.Retries,
+		backoff: durationOr(timeouts.RetryBackoff, defaultRetryBackoff),
+	}
+
+	switch protocol {
+	case "", model.UpstreamProtocolAuto:
+		return transport, retry, nil
+	case model.UpstreamProtocolHTTP1:
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		return transport, retry, nil
+	case model.UpstreamProtocolH2C:
+		h2c, err := newH2CTransport(transport)
+		return h2c, retry, err
+	default:
+		return nil, upstreamRetry{}, fmt.Errorf("unsupported upstream protocol: %s", protocol)
+	}
+}
+
+// newH2CTransport creates a transport that speaks HTTP/2 without TLS to the
+// local service. It shares the dialer and timeouts of transport.
+func newH2CTransport(transport *http.Transport) (http.RoundTripper, error) {
+	h2c, err := http2.ConfigureTransports(transport)
+	if err != nil {
+		return nil, err
+	}
+	// The pool of ConfigureTransports only reuses connections that transport
+	// upgraded through TLS; the default pool dials its own
+	h2c.ConnPool = nil
+	h2c.AllowHTTP = true
+	h2c.DialTLSContext = func(ctx context.Context, network string, addr string, _ *tls.Config) (net.Conn, error) {
+		return transport.DialContext(ctx, network, addr)
+	}
+	return h2c, nil
+}
+
+// do sends a request to the local service. Idempotent requests refused by
+// the local service are retried with a growing backoff when the tunnel
+// allows retries.
+func (t *httpTunnel) do(req *http.Request, logger port.Logger) (*http.Response, error) {
+	resp, err := t.client.Do(req)
+
+	backoff := t.retry.backoff
+	for attempt := 1; err != nil && attempt <= t.retry.retries && isRetryableRequest(req, err); attempt++ {
+		logger.Warn("Layanan lokal menolak koneksi, mencoba lagi dalam %s (%d/%d)", backoff, attempt, t.retry.retries)
+		time.Sleep(backoff)
+		backoff *= 2
+
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = t.client.Do(retry)
+	}
+
+	return resp, err
+}
+
+// isRetryableRequest reports whether a failed request may be sent again: its
+// method must be idempotent and the local service must have refused the
+// connection, so the request never reached it
+func isRetryableRequest(req *http.Request, err error) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	// Unix sockets disappear while the service restarts instead of refusing connections
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT)
+}
+
+// durationOr returns d, or fallback when d is not set
+func durationOr(d time.Duration, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
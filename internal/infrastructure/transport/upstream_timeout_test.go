@@ -0,0 +1,285 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/alwanandri2712/haxorport-go-client/internal/domain/model"
+	"github.com/alwanandri2712/haxorport-go-client/internal/infrastructure/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// countingTransport counts the requests sent through a transport
+type countingTransport struct {
+	http.RoundTripper
+	requests int32
+	// after is called after each request
+	after func(n int32)
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := c.RoundTripper.RoundTrip(req)
+	n := atomic.AddInt32(&c.requests, 1)
+	if c.after != nil {
+		c.after(n)
+	}
+	return resp, err
+}
+
+// closedPort returns a local port nobody listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+// newTestHTTPTunnel creates an HTTP tunnel whose upstream requests are counted
+func newTestHTTPTunnel(t *testing.T, config model.TunnelConfig) (*httpTunnel, *countingTransport) {
+	t.Helper()
+
+	config.Type = model.TunnelTypeHTTP
+	tunnel, err := newHTTPTunnel(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tunnel.close)
+	counter := &countingTransport{RoundTripper: tunnel.client.Transport}
+	tunnel.client.Transport = counter
+	return tunnel, counter
+}
+
+func TestIsRetryableRequest(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	missingSocket := &net.OpError{Op: "dial", Net: "unix", Err: os.NewSyscallError("connect", syscall.ENOENT)}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	tests := []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{http.MethodGet, refused, true},
+		{http.MethodHead, refused, true},
+		{http.MethodPut, refused, true},
+		{http.MethodDelete, refused, true},
+		{http.MethodOptions, missingSocket, true},
+		{http.MethodPost, refused, false},
+		{http.MethodPatch, refused, false},
+		{http.MethodGet, reset, false},
+		{http.MethodGet, context.DeadlineExceeded, false},
+		{http.MethodGet, errors.New("EOF"), false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "http://localhost/", nil)
+		if got := isRetryableRequest(req, fmt.Errorf("wrapped: %w", tt.err)); got != tt.want {
+			t.Errorf("isRetryableRequest(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestUpstreamRetriesRefusedRequests(t *testing.T) {
+	log := logger.NewLogger(io.Discard, "error")
+	port := closedPort(t)
+	timeouts := &model.UpstreamTimeoutConfig{Retries: 2, RetryBackoff: 20 * time.Millisecond}
+
+	tests := []struct {
+		method   string
+		requests int32
+	}{
+		{http.MethodGet, 3},
+		{http.MethodPost, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			tunnel, counter := newTestHTTPTunnel(t, model.TunnelConfig{LocalPort: port, Timeouts: timeouts})
+			req, _ := http.NewRequest(tt.method, fmt.Sprintf("http://127.0.0.1:%d/", port), strings.NewReader("payload"))
+
+			start := time.Now()
+			if _, err := tunnel.do(req, log); err == nil {
+				t.Fatal("request to a closed port succeeded")
+			}
+			if counter.requests != tt.requests {
+				t.Errorf("%d requests sent, want %d", counter.requests, tt.requests)
+			}
+			// Two retries wait 20ms and 40ms
+			if elapsed := time.Since(start); tt.requests == 3 && elapsed < 60*time.Millisecond {
+				t.Errorf("retries took %s, want the backoff of at least 60ms", elapsed)
+			}
+		})
+	}
+}
+
+func TestUpstreamRetryReachesRestartedService(t *testing.T) {
+	port := closedPort(t)
+	received := make(chan string, 1)
+	local := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	})}
+	defer local.Close()
+
+	tunnel, counter := newTestHTTPTunnel(t, model.TunnelConfig{
+		LocalPort: port,
+		Timeouts:  &model.UpstreamTimeoutConfig{Retries: 3, RetryBackoff: 10 * time.Millisecond},
+	})
+	// The local service comes back after the first refused request
+	counter.after = func(n int32) {
+		if n != 1 {
+			return
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		go local.Serve(listener)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("http://127.0.0.1:%d/", port), strings.NewReader("payload"))
+	resp, err := tunnel.do(req, logger.NewLogger(io.Discard, "error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if counter.requests != 2 {
+		t.Errorf("%d requests sent, want 2", counter.requests)
+	}
+	if body := <-received; body != "payload" {
+		t.Errorf("retried request body = %q, want %q", body, "payload")
+	}
+}
+
+func TestUpstreamResponseTimeout(t *testing.T) {
+	var requests int32
+	local := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer local.Close()
+
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	if _, err := repo.client.SendRegisterTunnel(model.TunnelConfig{
+		Type:      model.TunnelTypeHTTP,
+		LocalPort: localPort(t, local),
+		Timeouts:  &model.UpstreamTimeoutConfig{ResponseHeader: 50 * time.Millisecond, Retries: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := server.roundTrip(repo.client, &model.HTTPRequest{ID: "slow", TunnelID: "tunnel-http", Method: http.MethodGet, URL: "/", Headers: http.Header{"Host": {"app.haxorport.online"}}})
+	if response.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusGatewayTimeout)
+	}
+	// Timed out requests may have reached the local service and are not retried
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("local service got %d requests, want 1", n)
+	}
+}
+
+func TestNewUpstreamTransportRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts *model.UpstreamTimeoutConfig
+		protocol model.UpstreamProtocol
+	}{
+		{"negative timeout", &model.UpstreamTimeoutConfig{ResponseHeader: -time.Second}, ""},
+		{"negative retries", &model.UpstreamTimeoutConfig{Retries: -1}, ""},
+		{"unknown protocol", nil, "http3"},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := newUpstreamTransport(tt.timeouts, tt.protocol, nil); err == nil {
+			t.Errorf("%s: newUpstreamTransport succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestHTTPUpstreamH2C(t *testing.T) {
+	type received struct {
+		proto    string
+		body     string
+		trailers http.Header
+	}
+	requests := make(chan received, 1)
+	local := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Request trailers are only known after the body was read
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{proto: r.Proto, body: string(body), trailers: r.Trailer.Clone()}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Write([]byte("reply"))
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set("Grpc-Message", "OK")
+	}), &http2.Server{}))
+	defer local.Close()
+
+	server := newFakeServer(t)
+	repo := newTestRepository(t, server)
+	if _, err := repo.client.SendRegisterTunnel(model.TunnelConfig{
+		Type:      model.TunnelTypeHTTP,
+		LocalPort: localPort(t, local),
+		Protocol:  model.UpstreamProtocolH2C,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	response := server.roundTrip(repo.client, &model.HTTPRequest{
+		ID:       "grpc",
+		TunnelID: "tunnel-http",
+		Method:   http.MethodPost,
+		URL:      "/helloworld.Greeter/SayHello",
+		Headers:  http.Header{"Host": {"app.haxorport.online"}, "Content-Type": {"application/grpc"}, "Te": {"trailers"}},
+		Body:     []byte("request"),
+		Trailers: http.Header{"X-Checksum": {"abc123"}},
+	})
+
+	got := <-requests
+	if got.proto != "HTTP/2.0" {
+		t.Errorf("upstream request protocol = %s, want HTTP/2.0", got.proto)
+	}
+	if got.body != "request" || got.trailers.Get("X-Checksum") != "abc123" {
+		t.Errorf("upstream request body %q, trailers %v; want the body and X-Checksum", got.body, got.trailers)
+	}
+	if response.StatusCode != http.StatusOK || string(response.Body) != "reply" {
+		t.Errorf("response = %d %q, want 200 reply", response.StatusCode, response.Body)
+	}
+	if response.Trailers.Get("Grpc-Status") != "0" || response.Trailers.Get("Grpc-Message") != "OK" {
+		t.Errorf("response trailers = %v, want Grpc-Status and Grpc-Message", response.Trailers)
+	}
+}
+
+func TestNewHTTPTunnelRejectsH2CWithHTTPS(t *testing.T) {
+	if _, err := newHTTPTunnel(model.TunnelConfig{Type: model.TunnelTypeHTTP, LocalPort: 8443, Scheme: "https", Protocol: model.UpstreamProtocolH2C}); err == nil {
+		t.Error("h2c with an https upstream was accepted")
+	}
+	tunnel, err := newHTTPTunnel(model.TunnelConfig{Type: model.TunnelTypeHTTP, LocalPort: 8080, Scheme: "http", Protocol: model.UpstreamProtocolH2C})
+	if err != nil {
+		t.Fatalf("h2c with an http upstream: %v", err)
+	}
+	tunnel.close()
+}